@@ -0,0 +1,116 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+// ErrAdaptationNotTested is returned when saving an adaptation value that was not successfully tested first
+var ErrAdaptationNotTested = errors.New("adaptation value must be tested before it is saved")
+
+// Adaptation is the value of an adaptation channel
+type Adaptation struct {
+	Channel byte
+	Value   uint16
+}
+
+// ReadAdaptation reads the stored value of an adaptation channel.
+func (c *Connection) ReadAdaptation(ctx context.Context, channel byte) (uint16, error) {
+	a, err := c.adaptation(ctx, &Block{
+		Type: BlockTypeReadAdaptation,
+		Data: []byte{channel},
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to read adaptation channel %d", channel)
+	}
+	if a.Channel != channel {
+		return 0, errors.Errorf("requested adaptation channel %d but ecu responded with %d", channel, a.Channel)
+	}
+	return a.Value, nil
+}
+
+// TestAdaptation has the ECU use a new value for an adaptation channel without storing it, so the effect
+// can be checked before calling SaveAdaptation with the same value. Login is required.
+func (c *Connection) TestAdaptation(ctx context.Context, channel byte, value uint16) error {
+	if err := c.requireLogin(); err != nil {
+		return err
+	}
+
+	c.stateMu.Lock()
+	c.testedAdaptation = nil
+	c.stateMu.Unlock()
+
+	a := Adaptation{Channel: channel, Value: value}
+	if err := c.verifyAdaptation(ctx, &Block{
+		Type: BlockTypeTestAdaptation,
+		Data: []byte{channel, byte(value >> 8), byte(value)},
+	}, a); err != nil {
+		return errors.Wrapf(err, "unable to test adaptation channel %d", channel)
+	}
+
+	c.stateMu.Lock()
+	c.testedAdaptation = &a
+	c.stateMu.Unlock()
+	return nil
+}
+
+// SaveAdaptation permanently stores the value of an adaptation channel, recording the workshop code of the
+// change. The same value must have been successfully tested with TestAdaptation first. Login is required.
+func (c *Connection) SaveAdaptation(ctx context.Context, channel byte, value uint16, workshopCode uint32) error {
+	if err := c.requireLogin(); err != nil {
+		return err
+	}
+	a := Adaptation{Channel: channel, Value: value}
+	c.stateMu.Lock()
+	tested := c.testedAdaptation
+	c.stateMu.Unlock()
+	if tested == nil || *tested != a {
+		return ErrAdaptationNotTested
+	}
+
+	if err := c.verifyAdaptation(ctx, &Block{
+		Type: BlockTypeSaveAdaptation,
+		Data: []byte{channel, byte(value >> 8), byte(value),
+			byte(workshopCode >> 16), byte(workshopCode >> 8), byte(workshopCode)},
+	}, a); err != nil {
+		return errors.Wrapf(err, "unable to save adaptation channel %d", channel)
+	}
+
+	c.stateMu.Lock()
+	c.testedAdaptation = nil
+	c.stateMu.Unlock()
+	return nil
+}
+
+// send an adaptation block and check that the ECU responds with the expected channel and value
+func (c *Connection) verifyAdaptation(ctx context.Context, blk *Block, expected Adaptation) error {
+	a, err := c.adaptation(ctx, blk)
+	if err != nil {
+		return err
+	}
+	if a != expected {
+		return errors.Errorf("ecu responded with channel %d value %d", a.Channel, a.Value)
+	}
+	return nil
+}
+
+func (c *Connection) adaptation(ctx context.Context, blk *Block) (Adaptation, error) {
+	blocks, err := c.request(ctx, &request{
+		blk:       blk,
+		replyType: BlockTypeAdaptation,
+	})
+	if err != nil {
+		return Adaptation{}, err
+	}
+	if len(blocks) == 0 {
+		return Adaptation{}, errors.New("ecu did not respond with an adaptation value")
+	}
+	data := blocks[len(blocks)-1].Data
+	if len(data) < 3 {
+		return Adaptation{}, errors.Errorf("adaptation response must be at least 3 bytes but was %d", len(data))
+	}
+	return Adaptation{
+		Channel: data[0],
+		Value:   uint16(data[1])<<8 | uint16(data[2]),
+	}, nil
+}
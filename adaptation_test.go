@@ -0,0 +1,80 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReadAdaptation(t *testing.T) {
+	c, m := connection()
+	stageRequest(m, BlockTypeReadAdaptation, []byte{0x01},
+		&Block{Type: BlockTypeAdaptation, Data: []byte{0x01, 0x01, 0x2c}})
+
+	var value uint16
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		value, err = c.ReadAdaptation(context.Background(), 1)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(300), value)
+
+	c, m = connection()
+	stageRequest(m, BlockTypeReadAdaptation, []byte{0x01},
+		&Block{Type: BlockTypeAdaptation, Data: []byte{0x02, 0x01, 0x2c}})
+	err = runRequest(t, c, Callbacks{}, func() error {
+		_, err := c.ReadAdaptation(context.Background(), 1)
+		return err
+	})
+	assert.Error(t, err, "response for wrong channel should fail")
+}
+
+func TestTestAdaptation(t *testing.T) {
+	c, _ := connection()
+	assert.Equal(t, ErrLoginRequired, c.TestAdaptation(context.Background(), 1, 320))
+
+	c, m := connection()
+	c.loggedIn = true
+	stageRequest(m, BlockTypeTestAdaptation, []byte{0x01, 0x01, 0x40},
+		&Block{Type: BlockTypeAdaptation, Data: []byte{0x01, 0x01, 0x40}})
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		return c.TestAdaptation(context.Background(), 1, 320)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Adaptation{Channel: 1, Value: 320}, c.testedAdaptation)
+
+	// ECU responds with the existing value when the new value is rejected
+	c, m = connection()
+	c.loggedIn = true
+	stageRequest(m, BlockTypeTestAdaptation, []byte{0x01, 0xff, 0xff},
+		&Block{Type: BlockTypeAdaptation, Data: []byte{0x01, 0x01, 0x2c}})
+	err = runRequest(t, c, Callbacks{}, func() error {
+		return c.TestAdaptation(context.Background(), 1, 0xffff)
+	})
+	assert.Error(t, err)
+	assert.Nil(t, c.testedAdaptation)
+}
+
+func TestSaveAdaptation(t *testing.T) {
+	c, _ := connection()
+	assert.Equal(t, ErrLoginRequired, c.SaveAdaptation(context.Background(), 1, 320, 12345))
+
+	c.loggedIn = true
+	assert.Equal(t, ErrAdaptationNotTested, c.SaveAdaptation(context.Background(), 1, 320, 12345))
+
+	c.testedAdaptation = &Adaptation{Channel: 1, Value: 300}
+	assert.Equal(t, ErrAdaptationNotTested, c.SaveAdaptation(context.Background(), 1, 320, 12345),
+		"a different value was tested")
+
+	c, m := connection()
+	c.loggedIn = true
+	c.testedAdaptation = &Adaptation{Channel: 1, Value: 320}
+	stageRequest(m, BlockTypeSaveAdaptation, []byte{0x01, 0x01, 0x40, 0x00, 0x30, 0x39},
+		&Block{Type: BlockTypeAdaptation, Data: []byte{0x01, 0x01, 0x40}})
+	err := runRequest(t, c, Callbacks{}, func() error {
+		return c.SaveAdaptation(context.Background(), 1, 320, 12345)
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, c.testedAdaptation, "saving again requires another test")
+}
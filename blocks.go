@@ -3,6 +3,7 @@ package kw1281
 import (
 	"fmt"
 	"github.com/pkg/errors"
+	"time"
 )
 
 type BlockType byte
@@ -12,9 +13,21 @@ const (
 	BlockTypeGetErrors                     = 0x07
 	BlockTypeErrors                        = 0xfc
 	BlockTypeEndOutput                     = 0x06
+	BlockTypeOutputTest                    = 0x04
+	BlockTypeOutputTestResponse            = 0xf5
+	BlockTypeReadAdaptation                = 0x21
+	BlockTypeTestAdaptation                = 0x22
+	BlockTypeSaveAdaptation                = 0x2a
+	BlockTypeAdaptation                    = 0xe6
+	BlockTypeLogin                         = 0x2b
+	BlockTypeGetIdentification             = 0x00
+	BlockTypeRecoding                      = 0x10
 	BlockTypeACK                           = 0x09
+	BlockTypeNAK                           = 0x0a
 	BlockTypeGetMeasurementGroup           = 0x29
 	BlockTypeMeasurementGroup              = 0xe7
+	BlockTypeGetBasicSettings              = 0x28
+	BlockTypeBasicSettings                 = 0xf4
 	BlockTypeASCII                         = 0xf6
 	BlockTypeNull                          = 0x00
 
@@ -24,53 +37,95 @@ const (
 type Block struct {
 	Type BlockType
 	Data []byte
+
+	// when the block was received and the round-trip time since the previous block was sent
+	received time.Time
+	latency  time.Duration
 }
 
 type MeasurementValue struct {
+	// the decoded value as an int, float64, string or []byte. Kind and the typed accessors such as Float
+	// describe the value without switching on its type.
 	Value interface{}
 	Units string
+	// set when the formula is unknown, Value then holds the formula followed by the two raw data bytes
+	Undecoded bool
+	// the formula followed by the two data bytes the value was decoded from
+	Raw [3]byte
+
+	// typed value, set by the constructors in values.go that every formula uses
+	kind   ValueKind
+	number float64
+	text   string
+	bytes  []byte
 }
 
 type Measurement struct {
 	Metric Metric
+	Label  Label
 	*MeasurementValue
+	// when the measurement block was received and its round-trip time from the request or ACK sent to the ECU
+	Timestamp time.Time
+	Latency   time.Duration
+	// set when the measurement was read in basic settings mode rather than normal group reading
+	BasicSettings bool
 }
 
 func (b *Block) convert(group MeasurementGroup) ([]*Measurement, error) {
-	if b.Type != BlockTypeMeasurementGroup {
+	return b.convertMap(MeasurementMap, group)
+}
+
+func (b *Block) convertMap(groups MeasurementGroupMap, group MeasurementGroup) ([]*Measurement, error) {
+	if b.Type != BlockTypeMeasurementGroup && b.Type != BlockTypeBasicSettings {
 		return nil, errors.New("can only convert measurement blocks")
 	}
 	if len(b.Data) != 12 {
 		return nil, errors.Errorf("measurement data must be 12 bytes but was %d", len(b.Data))
 	}
 
-	measurements := make([]*Measurement, 4)
-	mapping, ok := MeasurementMap[group]
-	if !ok {
-		return nil, errors.Errorf("unknown measurement group %d", group)
+	if group < 0 || group > 0xff {
+		return nil, errors.Errorf("invalid measurement group %d", group)
 	}
 
+	measurements := make([]*Measurement, 4)
+	// groups without a layout are still decoded, their measurements have no metric or label
+	mapping := groups[group]
+
 	for n, data := range [][]byte{b.Data[0:3], b.Data[3:6], b.Data[6:9], b.Data[9:12]} {
-		m, err := dataToType(data)
-		if err != nil {
-			return nil, err
-		}
+		m := dataToType(data)
 		measurements[n] = &Measurement{
-			Metric: mapping.Metric[n],
-			MeasurementValue:  m,
+			Metric:           mapping.Metric[n],
+			Label:            mapping.Labels[n],
+			MeasurementValue: m,
+			Timestamp:        b.received,
+			Latency:          b.latency,
+			BasicSettings:    b.Type == BlockTypeBasicSettings,
 		}
 	}
 
 	return measurements, nil
 }
 
-func dataToType(data []byte) (*MeasurementValue, error) {
-	fn, ok := transformationMap[data[0]]
-	if !ok {
-		return nil, errors.Errorf("unknown measurement block transformation: %d", data[0])
+func dataToType(data []byte) *MeasurementValue {
+	return DecodeValue([3]byte{data[0], data[1], data[2]})
+}
+
+// DecodeValue decodes a measurement from its formula and two data bytes, e.g. to re-decode the Raw
+// bytes of previously logged measurements.
+func DecodeValue(raw [3]byte) *MeasurementValue {
+	fn, ok := lookupFormula(raw[0])
+	if !ok || fn == nil {
+		// deliver the raw data rather than failing the whole block
+		return rawValue(raw)
 	}
-	val := fn(data[1], data[2])
-	return &val, nil
+	val := fn(raw[1], raw[2])
+	val.Raw = raw
+	return &val
+}
+
+// Formula returns the identifier of the formula used to decode the value
+func (m *MeasurementValue) Formula() byte {
+	return m.Raw[0]
 }
 
 func (b *Block) Size() int {
@@ -79,5 +134,8 @@ func (b *Block) Size() int {
 }
 
 func (m *MeasurementValue) String() string {
+	if m.Undecoded {
+		return fmt.Sprintf("unknown formula %d: %#02x %#02x", m.Raw[0], m.Raw[1], m.Raw[2])
+	}
 	return fmt.Sprintf("%v %s", m.Value, m.Units)
 }
@@ -55,8 +55,10 @@ func TestConvert(t *testing.T) {
 		{[]byte{0x10, 0x1b, 0x01}, reflect.SliceOf(reflect.TypeOf(byte(0))), []byte{0x1, 0x1b}}}
 
 	for n, input := range testInputs {
-		m, err := dataToType(input.Data)
-		assert.NoError(t, err)
+		m := dataToType(input.Data)
+		assert.False(t, m.Undecoded)
+		assert.Equal(t, input.Data, m.Raw[:], "raw data not preserved for input %v", n)
+		assert.Equal(t, input.Data[0], m.Formula())
 		assert.Equal(t, input.ExpectedType, reflect.TypeOf(m.Value), "wrong type for input %v", n)
 		assert.Equal(t, input.ExpectedValue, m.Value, "not equal for input %v", n)
 
@@ -69,8 +71,26 @@ func TestConvert(t *testing.T) {
 		}
 	}
 
-	_, err := dataToType([]byte{0xff, 0xff, 0xff})
-	assert.Error(t, err, "no error returned with bad transformation")
+	m := dataToType([]byte{0xff, 0x12, 0x34})
+	assert.True(t, m.Undecoded, "unknown transformation not flagged")
+	assert.Equal(t, []byte{0xff, 0x12, 0x34}, m.Value)
+	assert.Equal(t, "unknown formula 255: 0x12 0x34", m.String())
+	assert.Equal(t, [3]byte{0xff, 0x12, 0x34}, m.Raw)
+
+	// formatted from the raw bytes whatever the value holds
+	m = &MeasurementValue{Undecoded: true, Raw: [3]byte{0xff, 0x12, 0x34}}
+	assert.Equal(t, "unknown formula 255: 0x12 0x34", m.String())
+}
+
+func TestDecodeValue(t *testing.T) {
+	m := DecodeValue([3]byte{0x05, 0x07, 0xd4})
+	assert.Equal(t, 78.4, m.Value)
+	assert.Equal(t, "C", m.Units)
+	assert.Equal(t, [3]byte{0x05, 0x07, 0xd4}, m.Raw)
+
+	// a logged measurement can be decoded again from its raw bytes
+	again := DecodeValue(m.Raw)
+	assert.Equal(t, m, again)
 }
 
 func TestBlockData(t *testing.T) {
@@ -89,9 +109,17 @@ func TestBlockData(t *testing.T) {
 	_, err = b.convert(5000)
 	assert.Error(t, err, "invalid group with valid block didn't error")
 
+	m, err := b.convert(7)
+	assert.NoError(t, err, "group without a layout fails the whole block")
+	assert.Equal(t, Metric(0), m[0].Metric)
+	assert.Equal(t, 4, m[0].Value)
+
 	b.Data[0] = 0x99
-	_, err = b.convert(GroupRPMCoolantTemp)
-	assert.Error(t, err, "invalid transformation function not detected by convert")
+	m, err = b.convert(GroupRPMCoolantTemp)
+	assert.NoError(t, err, "unknown transformation fails the whole block")
+	assert.True(t, m[0].Undecoded, "unknown transformation not flagged")
+	assert.False(t, m[1].Undecoded, "known transformation not decoded")
+	assert.Equal(t, 4, m[1].Value)
 }
 
 func TestWrongBlockData(t *testing.T) {
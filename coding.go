@@ -0,0 +1,103 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const (
+	// the coding is 15 bits and the workshop code the remaining 17 bits of a 4 byte value
+	maxCoding       = 0x7fff
+	maxWorkshopCode = 0x1ffff
+	codingLength    = 4
+)
+
+// add an identification block sent by the ECU. Most are text, however the coding and workshop code are
+// sent in a binary block.
+func (d *ECUDetails) add(data []byte) {
+	if d.isCodingBlock(data) {
+		d.Coding, d.WorkshopCode = decodeCoding(data)
+		d.hasCoding = true
+		return
+	}
+	str := strings.TrimSpace(string(data))
+	if len(d.PartNumber) == 0 {
+		d.PartNumber = str
+	} else {
+		d.Details = append(d.Details, str)
+	}
+}
+
+// the coding is the first 4 byte block after the part number and component description. Its content can't
+// be used to tell it apart from text, as any of its bytes may be printable and component descriptions may
+// contain non-ASCII characters.
+func (d *ECUDetails) isCodingBlock(data []byte) bool {
+	return len(data) == codingLength && !d.hasCoding && len(d.PartNumber) > 0 && len(d.Details) > 0
+}
+
+func decodeCoding(data []byte) (uint16, uint32) {
+	v := uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	return uint16(v >> 17), v & maxWorkshopCode
+}
+
+func encodeCoding(coding uint16, workshopCode uint32) []byte {
+	v := uint32(coding)<<17 | workshopCode
+	return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// ECUDetails returns the identification of the ECU, including the coding most recently read or written
+func (c *Connection) ECUDetails() ECUDetails {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.ecuDetails == nil {
+		return ECUDetails{}
+	}
+	return *c.ecuDetails
+}
+
+// ReadCoding requests the identification of the ECU and returns its software coding and the workshop code
+// of the tester that last coded it.
+func (c *Connection) ReadCoding(ctx context.Context) (uint16, uint32, error) {
+	details, err := c.identify(ctx)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "unable to read coding")
+	}
+	if !details.hasCoding {
+		return 0, 0, errors.New("ecu did not report its coding")
+	}
+	return details.Coding, details.WorkshopCode, nil
+}
+
+// WriteCoding changes the software coding of the ECU, recording the workshop code of the tester. The ECU
+// responds with its identification, which is checked to contain the new coding. Login is required.
+func (c *Connection) WriteCoding(ctx context.Context, coding uint16, workshopCode uint32) error {
+	if err := c.requireLogin(); err != nil {
+		return err
+	}
+	if coding > maxCoding {
+		return errors.Errorf("coding %d is larger than the maximum of %d", coding, maxCoding)
+	}
+	if workshopCode > maxWorkshopCode {
+		return errors.Errorf("workshop code %d is larger than the maximum of %d", workshopCode, maxWorkshopCode)
+	}
+
+	blocks, err := c.request(ctx, &request{blk: &Block{
+		Type: BlockTypeRecoding,
+		Data: encodeCoding(coding, workshopCode),
+	}})
+	if err != nil {
+		return errors.Wrap(err, "unable to write coding")
+	}
+	details, err := c.updateDetails(blocks)
+	if err != nil {
+		return errors.Wrap(err, "unable to verify coding")
+	}
+	if !details.hasCoding {
+		return errors.New("unable to verify coding, ecu did not report its coding")
+	}
+	if details.Coding != coding {
+		return errors.Errorf("ecu did not accept coding %05d, coding is %05d", coding, details.Coding)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeCoding(t *testing.T) {
+	data := encodeCoding(2, 12345)
+	assert.Equal(t, []byte{0x00, 0x04, 0x30, 0x39}, data)
+
+	coding, workshopCode := decodeCoding(data)
+	assert.Equal(t, uint16(2), coding)
+	assert.Equal(t, uint32(12345), workshopCode)
+
+	coding, workshopCode = decodeCoding(encodeCoding(maxCoding, maxWorkshopCode))
+	assert.Equal(t, uint16(maxCoding), coding)
+	assert.Equal(t, uint32(maxWorkshopCode), workshopCode)
+
+}
+
+func TestECUDetailsCodingBlock(t *testing.T) {
+	d := &ECUDetails{}
+	assert.False(t, d.isCodingBlock(encodeCoding(2, 12345)), "part number is sent first")
+	d.add([]byte("1J0959799AH "))
+	// component description in Latin-1
+	d.add([]byte("T\xfcrsteuerger\xe4t   FS 0003"))
+	assert.Equal(t, []string{"T\xfcrsteuerger\xe4t   FS 0003"}, d.Details, "non-ASCII text is not the coding")
+	assert.False(t, d.isCodingBlock([]byte{0x00, 0x04}), "too short for a coding block")
+
+	// coding and workshop code that are printable
+	printable := encodeCoding(8353, 17220)
+	assert.Equal(t, []byte("ABCD"), printable)
+	d.add(printable)
+	assert.Len(t, d.Details, 1, "coding block is not a detail string")
+	assert.Equal(t, uint16(8353), d.Coding)
+	assert.Equal(t, uint32(17220), d.WorkshopCode)
+
+	// only the first 4 byte block is the coding
+	d.add([]byte("0002"))
+	assert.Equal(t, uint16(8353), d.Coding)
+	assert.Len(t, d.Details, 2)
+}
+
+func TestStartupPhaseCoding(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	for _, data := range append(byteECUDetails, encodeCoding(1025, 54321)) {
+		ecuSendBytes(m, &counter, BlockTypeASCII, data)
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	}
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	ecuDetails, err := c.startupPhase()
+	assert.NoError(t, err)
+	assert.Equal(t, string(byteECUDetails[0]), ecuDetails.PartNumber)
+	assert.Len(t, ecuDetails.Details, 2, "coding block is not a detail string")
+	assert.Equal(t, uint16(1025), ecuDetails.Coding)
+	assert.Equal(t, uint32(54321), ecuDetails.WorkshopCode)
+}
+
+// identification blocks sent by the ECU followed by the coding block
+func identificationBlocks(coding []byte) []*Block {
+	var blocks []*Block
+	for _, data := range append(byteECUDetails, coding) {
+		blocks = append(blocks, &Block{Type: BlockTypeASCII, Data: data})
+	}
+	return append(blocks, &Block{Type: BlockTypeACK})
+}
+
+func TestReadCoding(t *testing.T) {
+	c, m := connection()
+	stageRequest(m, BlockTypeGetIdentification, []byte{}, identificationBlocks(encodeCoding(1025, 54321))...)
+
+	var coding uint16
+	var workshopCode uint32
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		coding, workshopCode, err = c.ReadCoding(context.Background())
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1025), coding)
+	assert.Equal(t, uint32(54321), workshopCode)
+	assert.Equal(t, uint16(1025), c.ECUDetails().Coding, "coding is surfaced through the ECU details")
+	assert.Equal(t, string(byteECUDetails[0]), c.ECUDetails().PartNumber)
+	assert.Len(t, c.ECUDetails().Details, 2, "identification text is kept")
+
+	// identification without a coding block
+	c, m = connection()
+	stageRequest(m, BlockTypeGetIdentification, []byte{}, identificationBlocks([]byte("line three"))...)
+	err = runRequest(t, c, Callbacks{}, func() error {
+		_, _, err := c.ReadCoding(context.Background())
+		return err
+	})
+	assert.Error(t, err)
+}
+
+func TestWriteCoding(t *testing.T) {
+	c, _ := connection()
+	assert.Equal(t, ErrLoginRequired, c.WriteCoding(context.Background(), 1025, 54321))
+	c.loggedIn = true
+	assert.Error(t, c.WriteCoding(context.Background(), maxCoding+1, 54321))
+	assert.Error(t, c.WriteCoding(context.Background(), 1025, maxWorkshopCode+1))
+
+	c, m := connection()
+	c.loggedIn = true
+	c.ecuDetails = &ECUDetails{PartNumber: "FAKE ECU 1.0", Coding: 2}
+	stageRequest(m, BlockTypeRecoding, encodeCoding(1025, 54321), identificationBlocks(encodeCoding(1025, 54321))...)
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		return c.WriteCoding(context.Background(), 1025, 54321)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1025), c.ECUDetails().Coding)
+	assert.Equal(t, uint32(54321), c.ECUDetails().WorkshopCode)
+
+	// ECU responds with the old coding when it rejects the new one
+	c, m = connection()
+	c.loggedIn = true
+	stageRequest(m, BlockTypeRecoding, encodeCoding(1025, 54321), identificationBlocks(encodeCoding(2, 12345))...)
+
+	err = runRequest(t, c, Callbacks{}, func() error {
+		return c.WriteCoding(context.Background(), 1025, 54321)
+	})
+	assert.Error(t, err)
+}
@@ -6,6 +6,17 @@ func complement(val byte) byte {
 	return 0xff - val
 }
 
+// returns true if an even number of bits are set
+func evenParity(val byte) bool {
+	even := true
+	for ; val != 0; val >>= 1 {
+		if val&0x1 == 1 {
+			even = !even
+		}
+	}
+	return even
+}
+
 // read and verify a value
 func (c *Connection) validateByte(val byte) error {
 	buf := make([]byte, 1)
@@ -12,7 +12,9 @@ import (
 type MockSerialPort struct {
 	ReadBuf  bytes.Buffer
 	WriteBuf bytes.Buffer
-	closed   bool
+	// logical state of the TX line each time a break is set or cleared
+	Bits   []bool
+	closed bool
 }
 
 func (port *MockSerialPort) Flush() error {
@@ -37,10 +39,12 @@ func (port *MockSerialPort) SetRtsOn() error {
 }
 
 func (port *MockSerialPort) SetBreakOff() error {
+	port.Bits = append(port.Bits, true)
 	return nil
 }
 
 func (port *MockSerialPort) SetBreakOn() error {
+	port.Bits = append(port.Bits, false)
 	return nil
 }
 
@@ -71,7 +75,7 @@ func connection() (*Connection, *MockSerialPort) {
 		},
 		counter:   1,
 		port:      m,
-		nextBlock: make(chan *Block, 1),
+		nextBlock: make(chan *request, 1),
 	}, m
 }
 
@@ -81,6 +85,15 @@ func TestComplement(t *testing.T) {
 	assert.Equal(t, uint8(0xe6), complement(25))
 }
 
+func TestEvenParity(t *testing.T) {
+	assert.True(t, evenParity(0))
+	assert.False(t, evenParity(0x01))
+	assert.True(t, evenParity(0x03))
+	assert.False(t, evenParity(0x15))
+	assert.True(t, evenParity(0x17))
+	assert.True(t, evenParity(0xff))
+}
+
 func TestValidateByte(t *testing.T) {
 	const testByte uint8 = 0x23
 	c, m := connection()
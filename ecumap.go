@@ -1,5 +1,10 @@
 package kw1281
 
+import (
+	"strings"
+	"sync"
+)
+
 type MeasurementGroup int
 
 const (
@@ -19,21 +24,88 @@ const (
 	MetricThrottleAngle
 	MetricAirIntakeTemp
 	MetricSpeed
+	MetricEngineLoad
+	MetricIgnitionTiming
+	MetricLambda
+	MetricAirMass
+	MetricOilTemp
+	MetricIntakePressure
+	MetricFuelConsumption
+
+	// metrics registered at runtime are allocated from here
+	metricFirstRegistered
 )
 
-var MeasurementMap = map[MeasurementGroup]struct {
+// MeasurementGroupMetrics describes the metric measured by each of the four cells of a measurement group
+type MeasurementGroupMetrics struct {
 	Metric [4]Metric
-}{
+	// optional names of the group and of each cell, e.g. from a label file
+	Name   string
+	Labels [4]Label
+}
+
+// Label names a cell of a measurement group
+type Label struct {
+	Name        string
+	Description string
+}
+
+// MeasurementGroupMap describes the layout of the measurement groups of an ECU
+type MeasurementGroupMap map[MeasurementGroup]MeasurementGroupMetrics
+
+// MeasurementMap is the generic layout used for ECUs without a registered map
+var MeasurementMap = MeasurementGroupMap{
 	GroupRPMCoolantTemp: {
-		[4]Metric{MetricRPM, MetricCoolantTemp, 0, 0},
+		Metric: [4]Metric{MetricRPM, MetricCoolantTemp, 0, 0},
 	},
 	GroupRPMBatteryInjectionTimeBlockNum: {
-		[4]Metric{MetricRPM, MetricInjectionTime, MetricBatteryVoltage, 0},
+		Metric: [4]Metric{MetricRPM, MetricInjectionTime, MetricBatteryVoltage, 0},
 	},
 	GroupRPMThrottleIntakeAirBlockNum: {
-		[4]Metric{MetricRPM, 0, MetricThrottleAngle, MetricAirIntakeTemp},
+		Metric: [4]Metric{MetricRPM, 0, MetricThrottleAngle, MetricAirIntakeTemp},
 	},
 	GroupRPMSpeedBlockNum: {
-		[4]Metric{MetricRPM, 0, MetricSpeed, 0},
+		Metric: [4]Metric{MetricRPM, 0, MetricSpeed, 0},
 	},
 }
+
+var measurementMaps = struct {
+	sync.RWMutex
+	m map[string]MeasurementGroupMap
+}{m: make(map[string]MeasurementGroupMap)}
+
+// RegisterMeasurementMap registers the measurement group layout of ECUs whose part number starts with
+// partNumber. Spaces, dashes and dots are ignored, so "8D0 907 551" matches every index of that part.
+func RegisterMeasurementMap(partNumber string, m MeasurementGroupMap) {
+	measurementMaps.Lock()
+	defer measurementMaps.Unlock()
+	measurementMaps.m[normalizePartNumber(partNumber)] = m
+}
+
+// LookupMeasurementMap returns the measurement group layout registered with the longest prefix of the
+// part number, or MeasurementMap if there is none.
+func LookupMeasurementMap(partNumber string) MeasurementGroupMap {
+	partNumber = normalizePartNumber(partNumber)
+
+	measurementMaps.RLock()
+	defer measurementMaps.RUnlock()
+	match := ""
+	mapping := MeasurementMap
+	for prefix, m := range measurementMaps.m {
+		if strings.HasPrefix(partNumber, prefix) && len(prefix) > len(match) {
+			match = prefix
+			mapping = m
+		}
+	}
+	return mapping
+}
+
+func normalizePartNumber(partNumber string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(partNumber)))
+}
@@ -0,0 +1,136 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/jd3nn1s/serial"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func registerTestMap(partNumber string, m MeasurementGroupMap) func() {
+	RegisterMeasurementMap(partNumber, m)
+	return func() {
+		measurementMaps.Lock()
+		defer measurementMaps.Unlock()
+		delete(measurementMaps.m, normalizePartNumber(partNumber))
+	}
+}
+
+func TestLookupMeasurementMap(t *testing.T) {
+	allIndexes := MeasurementGroupMap{
+		GroupRPMCoolantTemp: {Metric: [4]Metric{MetricCoolantTemp, MetricRPM, 0, 0}},
+	}
+	indexM := MeasurementGroupMap{
+		GroupRPMCoolantTemp: {Metric: [4]Metric{0, 0, MetricRPM, MetricCoolantTemp}},
+	}
+	defer registerTestMap("8D0 907 551", allIndexes)()
+	defer registerTestMap("8D0-907-551-M", indexM)()
+
+	assert.Equal(t, MeasurementMap, LookupMeasurementMap("FAKE ECU 1.0"), "unknown part falls back to generic map")
+	assert.Equal(t, MeasurementMap, LookupMeasurementMap(""), "empty part falls back to generic map")
+	assert.Equal(t, allIndexes, LookupMeasurementMap("8D0907551A  2.8l V6/5V G  0002"))
+	assert.Equal(t, indexM, LookupMeasurementMap("8d0907551m  2.8l V6/5V G  0002"), "longest prefix wins")
+}
+
+func TestNormalizePartNumber(t *testing.T) {
+	assert.Equal(t, "8D0907551M", normalizePartNumber(" 8d0-907.551 M "))
+}
+
+func TestDialSelectsMeasurementMap(t *testing.T) {
+	defer noDelays()()
+	ecuMap := MeasurementGroupMap{
+		GroupRPMCoolantTemp: {Metric: [4]Metric{MetricSpeed, MetricRPM, 0, 0}},
+	}
+	defer registerTestMap(string(byteECUDetails[0]), ecuMap)()
+
+	m := &MockSerialPort{}
+	oldOpenPort := openPort
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		return m, nil
+	}
+	defer func() {
+		openPort = oldOpenPort
+	}()
+
+	m.ReadBuf.Write([]byte{0x55, 0x01, 0x8a})
+	m.ReadBuf.Write([]byte{complement(0x8a)})
+	counter := uint8(1)
+	stageStartupPhaseData(m, &counter)
+
+	c, err := Connect("/dev/fakeport")
+	assert.NoError(t, err)
+	assert.Equal(t, ecuMap, c.MeasurementMap())
+}
+
+func TestStartUsesMeasurementMap(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+	c.groupMap = MeasurementGroupMap{
+		GroupRPMCoolantTemp: {Metric: [4]Metric{MetricSpeed, MetricRPM, 0, 0}},
+	}
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x07, 0xc0, 0x00,
+		0x01, 0xc8, 0x31,
+		0x01, 0xc8, 0x31,
+		0x01, 0xc8, 0x31})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	c.RequestMeasurementGroup(GroupRPMCoolantTemp)
+
+	var metrics []Metric
+	assert.Error(t, c.Start(context.Background(), Callbacks{
+		Measurement: func(group MeasurementGroup, measurements []*Measurement) {
+			for _, m := range measurements {
+				metrics = append(metrics, m.Metric)
+			}
+		},
+	}))
+	assert.Equal(t, []Metric{MetricSpeed, MetricRPM, 0, 0}, metrics)
+}
+
+func TestReadGroupUnmapped(t *testing.T) {
+	layout := MeasurementGroupMap{
+		GroupRPMCoolantTemp: {Metric: [4]Metric{MetricSpeed, MetricRPM, 0, 0}},
+	}
+	for _, tc := range []struct {
+		groupMap MeasurementGroupMap
+		group    MeasurementGroup
+		want     []Metric
+	}{
+		// not in the layout for the part number, which describes another ECU than the generic layout
+		{layout, GroupRPMThrottleIntakeAirBlockNum, []Metric{0, 0, 0, 0}},
+		// no layout for the part number, uses the generic layout
+		{nil, GroupRPMThrottleIntakeAirBlockNum, []Metric{MetricRPM, 0, MetricThrottleAngle, MetricAirIntakeTemp}},
+		// not in any layout
+		{nil, 7, []Metric{0, 0, 0, 0}},
+	} {
+		group := tc.group
+		c, m := connection()
+		counter := uint8(1)
+		c.groupMap = tc.groupMap
+
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+		ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(group)})
+		ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+			0x01, 0xc8, 0x31,
+			0x01, 0xc8, 0x31,
+			0x01, 0xc8, 0x31,
+			0x01, 0xc8, 0x31})
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+		var measurements []*Measurement
+		err := runRequest(t, c, Callbacks{}, func() (err error) {
+			measurements, err = c.ReadGroup(context.Background(), group)
+			return err
+		})
+		assert.NoError(t, err)
+		var metrics []Metric
+		for _, m := range measurements {
+			metrics = append(metrics, m.Metric)
+		}
+		assert.Equal(t, tc.want, metrics, "group %d", group)
+		assert.Equal(t, 392, measurements[0].Value)
+	}
+}
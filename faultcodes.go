@@ -0,0 +1,167 @@
+package kw1281
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FaultDatabase maps fault codes and elaboration codes to human readable descriptions.
+type FaultDatabase struct {
+	Faults       map[uint16]string
+	Elaborations map[byte]string
+}
+
+// DefaultFaultDatabase is used by new connections and contains commonly reported engine fault codes. Actuators
+// driven during output tests share the same numbering.
+var DefaultFaultDatabase = &FaultDatabase{
+	Faults: map[uint16]string{
+		513:   "Engine Speed Sender -G28-",
+		515:   "Hall Sender -G40-",
+		518:   "Throttle Position Sensor -G69-",
+		519:   "Intake Manifold Pressure Sensor -G71-",
+		522:   "Coolant Temperature Sensor -G62-",
+		523:   "Intake Air Temperature Sensor -G42-",
+		524:   "Knock Sensor 1 -G61-",
+		525:   "Oxygen Sensor -G39-",
+		532:   "Supply Voltage B+",
+		533:   "Idle Speed Control",
+		537:   "Oxygen Sensor Control",
+		540:   "Knock Sensor 2 -G66-",
+		543:   "Maximum Engine Speed Exceeded",
+		553:   "Mass Air Flow Sensor -G70-",
+		561:   "Mixture Adaptation",
+		575:   "Intake Manifold Pressure",
+		625:   "Speed Signal",
+		668:   "Supply Voltage Terminal 30",
+		1087:  "Basic Setting Not Carried Out",
+		1247:  "Solenoid Valve for Charcoal Filter System -N80-",
+		1249:  "Injector Cylinder 1 -N30-",
+		1250:  "Injector Cylinder 2 -N31-",
+		1251:  "Injector Cylinder 3 -N32-",
+		1252:  "Injector Cylinder 4 -N33-",
+		1259:  "Fuel Pump Relay -J17-",
+		1262:  "Solenoid Valve for Boost Pressure Control -N75-",
+		65535: "Internal Control Module Memory Error",
+	},
+	Elaborations: map[byte]string{
+		1:  "Signal Shorted to Plus",
+		2:  "Signal Shorted to Ground",
+		3:  "No Signal",
+		4:  "Mechanical Malfunction",
+		5:  "Input Open",
+		6:  "Signal too High",
+		7:  "Signal too Low",
+		8:  "Control Limit Surpassed",
+		9:  "Adaptation Limit Surpassed",
+		10: "Adaptation Limit Not Reached",
+		11: "Control Limit Not Reached",
+		12: "Adaptation Limit (Mul) Exceeded",
+		13: "Adaptation Limit (Mul) Not Reached",
+		14: "Adaptation Limit (Add) Exceeded",
+		15: "Adaptation Limit (Add) Not Reached",
+		16: "Signal Outside Specifications",
+		17: "Control Difference",
+		18: "Upper Limit",
+		19: "Lower Limit",
+		20: "Malfunction in Basic Setting",
+		25: "Unknown Switch Condition",
+		26: "Output Open",
+		27: "Implausible Signal",
+		28: "Short to Plus",
+		29: "Short to Ground",
+		30: "Open or Short to Plus",
+		31: "Open or Short to Ground",
+		32: "Resistance Too High",
+		33: "Resistance Too Low",
+		34: "No Basic Setting",
+		36: "Open Circuit",
+		37: "Faulty",
+		39: "Defective",
+	},
+}
+
+// NewFaultDatabase returns a copy of the default fault database that can be extended without affecting
+// other connections.
+func NewFaultDatabase() *FaultDatabase {
+	db := &FaultDatabase{
+		Faults:       make(map[uint16]string, len(DefaultFaultDatabase.Faults)),
+		Elaborations: make(map[byte]string, len(DefaultFaultDatabase.Elaborations)),
+	}
+	for code, desc := range DefaultFaultDatabase.Faults {
+		db.Faults[code] = desc
+	}
+	for code, desc := range DefaultFaultDatabase.Elaborations {
+		db.Elaborations[code] = desc
+	}
+	return db
+}
+
+// Load reads descriptions in CSV format and adds them to the database, replacing any existing description
+// for the same code. Each record is either a fault or an elaboration description:
+//
+//	# comment
+//	fault,00522,Coolant Temperature Sensor -G62-
+//	elaboration,29,Short to Ground
+func (db *FaultDatabase) Load(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "unable to read fault database")
+		}
+
+		desc := strings.TrimSpace(record[2])
+		switch record[0] {
+		case "fault":
+			code, err := strconv.ParseUint(record[1], 10, 16)
+			if err != nil {
+				return errors.Wrapf(err, "invalid fault code %q", record[1])
+			}
+			db.Faults[uint16(code)] = desc
+		case "elaboration":
+			code, err := strconv.ParseUint(record[1], 10, 7)
+			if err != nil {
+				return errors.Wrapf(err, "invalid elaboration code %q", record[1])
+			}
+			db.Elaborations[byte(code)] = desc
+		default:
+			return errors.Errorf("unknown record type %q", record[0])
+		}
+	}
+}
+
+// Describe returns the fault with the descriptions of its fault and elaboration codes filled in
+func (db *FaultDatabase) Describe(f Fault) Fault {
+	f.Description = db.Faults[f.Code]
+	f.ElaborationDescription = db.Elaborations[f.Elaboration()]
+	return f
+}
+
+// Text returns a human readable description of the fault, e.g.
+// "00522 Coolant Temperature Sensor -G62-: Short to Ground"
+func (f Fault) Text() string {
+	desc := f.Description
+	if desc == "" {
+		desc = "Unknown Fault Code"
+	}
+	elab := f.ElaborationDescription
+	if elab == "" {
+		elab = fmt.Sprintf("Elaboration %02d", f.Elaboration())
+	}
+	s := fmt.Sprintf("%05d %s: %s", f.Code, desc, elab)
+	if f.Intermittent() {
+		s += " (intermittent)"
+	}
+	return s
+}
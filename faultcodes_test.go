@@ -0,0 +1,50 @@
+package kw1281
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestDescribeFault(t *testing.T) {
+	f := DefaultFaultDatabase.Describe(Fault{Code: 522, Status: 0x1d})
+	assert.Equal(t, "Coolant Temperature Sensor -G62-", f.Description)
+	assert.Equal(t, "Short to Ground", f.ElaborationDescription)
+	assert.Equal(t, "00522 Coolant Temperature Sensor -G62-: Short to Ground", f.Text())
+
+	f = DefaultFaultDatabase.Describe(Fault{Code: 12345, Status: 0xfe})
+	assert.Empty(t, f.Description)
+	assert.Empty(t, f.ElaborationDescription)
+	assert.Equal(t, "12345 Unknown Fault Code: Elaboration 126 (intermittent)", f.Text())
+}
+
+func TestFaultDatabaseLoad(t *testing.T) {
+	db := NewFaultDatabase()
+	err := db.Load(strings.NewReader(`# ABS faults
+fault,01276,ABS Hydraulic Pump -V64-
+fault, 00522, Engine Coolant Temperature Sensor -G62-
+elaboration,126,Custom Elaboration
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "ABS Hydraulic Pump -V64-", db.Faults[1276])
+	assert.Equal(t, "Engine Coolant Temperature Sensor -G62-", db.Faults[522])
+	assert.Equal(t, "Custom Elaboration", db.Elaborations[126])
+
+	// the default database is unaffected
+	assert.Equal(t, "Coolant Temperature Sensor -G62-", DefaultFaultDatabase.Faults[522])
+	_, ok := DefaultFaultDatabase.Faults[1276]
+	assert.False(t, ok)
+}
+
+func TestFaultDatabaseLoadErrors(t *testing.T) {
+	for _, input := range []string{
+		"fault,00522",
+		"fault,abc,Bad Code",
+		"fault,70000,Code Too Large",
+		"elaboration,200,Elaboration Too Large",
+		"unknown,00522,Unknown Record Type",
+	} {
+		assert.Error(t, NewFaultDatabase().Load(strings.NewReader(input)), "input %q", input)
+	}
+}
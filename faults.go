@@ -0,0 +1,116 @@
+package kw1281
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// length of a single fault record within an errors block
+const faultRecordLength = 3
+
+// ECUs with an empty fault memory send a single record with this code and status, 65535 with any other
+// status is the internal control module memory error
+const (
+	noFaultsCode   uint16 = 0xffff
+	noFaultsStatus byte   = 0x88
+)
+
+// Fault is a diagnostic trouble code read from the fault memory of the ECU.
+type Fault struct {
+	Code   uint16
+	Status byte
+
+	// filled in from the fault database of the connection, empty if the code is unknown
+	Description            string
+	ElaborationDescription string
+}
+
+// Elaboration returns the elaboration code, describing what is wrong with the faulty component
+func (f Fault) Elaboration() byte {
+	return f.Status & 0x7f
+}
+
+// Intermittent returns true if the fault is sporadic rather than currently present
+func (f Fault) Intermittent() bool {
+	return f.Status&0x80 != 0
+}
+
+func (f Fault) String() string {
+	s := fmt.Sprintf("%05d-%02d", f.Code, f.Elaboration())
+	if f.Intermittent() {
+		s += " intermittent"
+	}
+	return s
+}
+
+// ReadFaults requests the contents of the fault memory from the ECU.
+func (c *Connection) ReadFaults(ctx context.Context) ([]Fault, error) {
+	blocks, err := c.request(ctx, &request{blk: &Block{Type: BlockTypeGetErrors}})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read faults")
+	}
+	return c.describeFaults(parseFaults(blocks))
+}
+
+func parseFaults(blocks []*Block) ([]Fault, error) {
+	faults := make([]Fault, 0)
+	for _, blk := range blocks {
+		if blk.Type != BlockTypeErrors {
+			return nil, errors.Errorf("expected errors block type but received %v", blk.Type)
+		}
+		if len(blk.Data)%faultRecordLength != 0 {
+			return nil, errors.Errorf("errors block length must be a multiple of %d but was %d",
+				faultRecordLength, len(blk.Data))
+		}
+		for i := 0; i < len(blk.Data); i += faultRecordLength {
+			f := Fault{
+				Code:   uint16(blk.Data[i])<<8 | uint16(blk.Data[i+1]),
+				Status: blk.Data[i+2],
+			}
+			if f.Code == noFaultsCode && f.Status == noFaultsStatus {
+				continue
+			}
+			faults = append(faults, f)
+		}
+	}
+	return faults, nil
+}
+
+// ClearFaults requests that the ECU erases its fault memory. Some ECUs respond with the contents of the
+// fault memory after clearing, in which case any faults that are still present are returned.
+func (c *Connection) ClearFaults(ctx context.Context) ([]Fault, error) {
+	blocks, err := c.request(ctx, &request{
+		blk:        &Block{Type: BlockTypeClearErrors},
+		nakRefuses: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to clear faults")
+	}
+	return c.describeFaults(parseFaults(blocks))
+}
+
+// FaultDatabase returns the database used to describe faults read from the ECU
+func (c *Connection) FaultDatabase() *FaultDatabase {
+	if c.faultDB == nil {
+		return DefaultFaultDatabase
+	}
+	return c.faultDB
+}
+
+// SetFaultDatabase replaces the database used to describe faults read from the ECU, e.g. with one
+// extended for a particular ECU family.
+func (c *Connection) SetFaultDatabase(db *FaultDatabase) {
+	c.faultDB = db
+}
+
+func (c *Connection) describeFaults(faults []Fault, err error) ([]Fault, error) {
+	if err != nil {
+		return nil, err
+	}
+	db := c.FaultDatabase()
+	for i := range faults {
+		faults[i] = db.Describe(faults[i])
+	}
+	return faults, nil
+}
@@ -0,0 +1,140 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseFaults(t *testing.T) {
+	faults, err := parseFaults([]*Block{
+		{Type: BlockTypeErrors, Data: []byte{0x02, 0x0a, 0x1d, 0x02, 0x1b, 0xa3}},
+		{Type: BlockTypeErrors, Data: []byte{0x02, 0x0d, 0x06}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, faults, 3)
+
+	assert.Equal(t, uint16(522), faults[0].Code)
+	assert.Equal(t, byte(29), faults[0].Elaboration())
+	assert.False(t, faults[0].Intermittent())
+	assert.Equal(t, "00522-29", faults[0].String())
+
+	assert.Equal(t, uint16(539), faults[1].Code)
+	assert.Equal(t, byte(35), faults[1].Elaboration())
+	assert.True(t, faults[1].Intermittent())
+	assert.Equal(t, "00539-35 intermittent", faults[1].String())
+
+	assert.Equal(t, uint16(525), faults[2].Code)
+}
+
+func TestParseNoFaults(t *testing.T) {
+	faults, err := parseFaults([]*Block{
+		{Type: BlockTypeErrors, Data: []byte{0xff, 0xff, 0x88}},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, faults)
+}
+
+func TestParseFaultMemoryError(t *testing.T) {
+	faults, err := parseFaults([]*Block{
+		{Type: BlockTypeErrors, Data: []byte{0xff, 0xff, 0x23}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Fault{{Code: 65535, Status: 0x23}}, faults)
+}
+
+func TestParseFaultsErrors(t *testing.T) {
+	_, err := parseFaults([]*Block{
+		{Type: BlockTypeErrors, Data: []byte{0x02, 0x0a}},
+	})
+	assert.Error(t, err, "truncated fault record should fail")
+
+	_, err = parseFaults([]*Block{
+		{Type: BlockTypeASCII, Data: []byte{0x02, 0x0a, 0x1d}},
+	})
+	assert.Error(t, err, "wrong block type should fail")
+}
+
+func TestReadFaults(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	// ECU send ACK on start
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// echo back request for faults
+	ecuSendBytes(m, &counter, BlockTypeGetErrors, []byte{})
+	// ECU send two errors blocks, each responded to with an ACK
+	ecuSendBytes(m, &counter, BlockTypeErrors, []byte{0x02, 0x0a, 0x1d})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeErrors, []byte{0x02, 0x1b, 0xa3})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// ECU send ACK to end the response
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	var faults []Fault
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		faults, err = c.ReadFaults(context.Background())
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Fault{
+		{Code: 522, Status: 0x1d, Description: "Coolant Temperature Sensor -G62-", ElaborationDescription: "Short to Ground"},
+		{Code: 539, Status: 0xa3},
+	}, faults)
+}
+
+func TestReadFaultsConnectionError(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	// ECU send ACK on start
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// echo back request for faults, but never respond
+	ecuSendBytes(m, &counter, BlockTypeGetErrors, []byte{})
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		_, err := c.ReadFaults(context.Background())
+		return err
+	})
+	assert.Error(t, err, "request should fail when the Start loop ends")
+}
+
+func clearFaults(t *testing.T, responses ...*Block) ([]Fault, error) {
+	c, m := connection()
+	stageRequest(m, BlockTypeClearErrors, []byte{}, responses...)
+
+	var faults []Fault
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		faults, err = c.ClearFaults(context.Background())
+		return err
+	})
+	return faults, err
+}
+
+func TestClearFaultsACK(t *testing.T) {
+	faults, err := clearFaults(t, &Block{Type: BlockTypeACK})
+	assert.NoError(t, err)
+	assert.Empty(t, faults)
+}
+
+func TestClearFaultsNAK(t *testing.T) {
+	_, err := clearFaults(t, &Block{Type: BlockTypeNAK})
+	assert.Error(t, err, "ECU refusing to clear faults should fail")
+}
+
+func TestClearFaultsRefreshedList(t *testing.T) {
+	faults, err := clearFaults(t,
+		&Block{Type: BlockTypeErrors, Data: []byte{0x02, 0x0a, 0x1d}},
+		&Block{Type: BlockTypeACK})
+	assert.NoError(t, err)
+	assert.Len(t, faults, 1)
+	assert.Equal(t, uint16(522), faults[0].Code)
+	assert.Equal(t, byte(0x1d), faults[0].Status)
+
+	faults, err = clearFaults(t,
+		&Block{Type: BlockTypeErrors, Data: []byte{0xff, 0xff, 0x88}},
+		&Block{Type: BlockTypeACK})
+	assert.NoError(t, err)
+	assert.Empty(t, faults)
+}
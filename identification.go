@@ -0,0 +1,131 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// hardware and software version at the end of the component description, e.g. "2.8l V6/5V G   0002",
+	// "MOTR    HS D02" or "VDO H03 V01"
+	versionPattern = regexp.MustCompile(`^(.*?)(?:\s+(H[0-9]{2}))?\s+([A-Z]?[0-9]{2,4})$`)
+	// immobilizer identifier, e.g. "VWZ7Z0X1234567"
+	immobilizerPattern = regexp.MustCompile(`^[A-Z]{3}[0-9A-Z]{11}$`)
+	// dealer or importer number sent as text by some instrument clusters, e.g. "WSC 01234"
+	dealerCodePattern = regexp.MustCompile(`^(?i:WSC|DEALER|IMPORTER)\s*:?\s*([0-9]{1,5})$`)
+)
+
+// Identification describes an ECU, parsed from the identification blocks it sends
+type Identification struct {
+	PartNumber string
+	// the part number split into its base and index, zero if it is not a VAG part number
+	Part            PartNumber
+	Component       string
+	SoftwareVersion string
+	HardwareVersion string
+	Coding          uint16
+	WorkshopCode    uint32
+	// only reported by ECUs that are part of the immobilizer system
+	ImmobilizerID string
+	// only reported as text by some ECUs, the workshop code of the last coding is in WorkshopCode
+	DealerCode uint32
+
+	// the identification text as sent by the ECU
+	Raw []string
+}
+
+// Identification parses the text sent by the ECU during startup into structured fields
+func (d *ECUDetails) Identification() Identification {
+	id := Identification{
+		Coding:       d.Coding,
+		WorkshopCode: d.WorkshopCode,
+		Raw:          append([]string{d.PartNumber}, d.Details...),
+	}
+
+	// the part number can be followed by the start of the component description in the same block
+	first := strings.TrimSpace(d.PartNumber)
+	var rest string
+	if part, n, ok := parsePartNumberPrefix(first); ok {
+		id.PartNumber = first[:n]
+		id.Part = part
+		rest = first[n:]
+	} else {
+		fields := strings.SplitN(first, " ", 2)
+		id.PartNumber = fields[0]
+		if len(fields) == 2 {
+			rest = fields[1]
+		}
+	}
+	var component []string
+	if rest = strings.TrimSpace(rest); rest != "" {
+		component = append(component, rest)
+	}
+
+	for _, detail := range d.Details {
+		switch {
+		case detail == "":
+		case immobilizerPattern.MatchString(detail):
+			id.ImmobilizerID = detail
+		case dealerCodePattern.MatchString(detail):
+			code, _ := strconv.ParseUint(dealerCodePattern.FindStringSubmatch(detail)[1], 10, 32)
+			id.DealerCode = uint32(code)
+		default:
+			component = append(component, detail)
+		}
+	}
+
+	id.Component = strings.Join(component, " ")
+	if m := versionPattern.FindStringSubmatch(id.Component); m != nil {
+		id.Component = m[1]
+		id.HardwareVersion = m[2]
+		id.SoftwareVersion = m[3]
+	}
+	return id
+}
+
+// Identify requests the identification of the ECU. It can be used at any time, e.g. to read the
+// immobilizer identifier or check the coding after a change, and the ECU details are updated with the
+// result.
+func (c *Connection) Identify(ctx context.Context) (Identification, error) {
+	details, err := c.identify(ctx)
+	if err != nil {
+		return Identification{}, err
+	}
+	return details.Identification(), nil
+}
+
+func (c *Connection) identify(ctx context.Context) (ECUDetails, error) {
+	blocks, err := c.request(ctx, &request{blk: &Block{Type: BlockTypeGetIdentification}})
+	if err != nil {
+		return ECUDetails{}, errors.Wrap(err, "unable to identify ecu")
+	}
+	return c.updateDetails(blocks)
+}
+
+// parse the identification blocks sent by the ECU and replace the stored ECU details with them
+func (c *Connection) updateDetails(blocks []*Block) (ECUDetails, error) {
+	details := ECUDetails{}
+	for _, blk := range blocks {
+		if blk.Type != BlockTypeASCII {
+			return ECUDetails{}, errors.Errorf("expected ascii block type but received %v", blk.Type)
+		}
+		details.add(blk.Data)
+	}
+	if len(details.PartNumber) == 0 {
+		return ECUDetails{}, errors.New("ecu did not report its part number")
+	}
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.ecuDetails == nil {
+		c.ecuDetails = &ECUDetails{}
+	}
+	// found during initialization rather than sent in the identification
+	details.Baud = c.ecuDetails.Baud
+	details.Keyword = c.ecuDetails.Keyword
+	*c.ecuDetails = details
+	return details, nil
+}
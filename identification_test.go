@@ -0,0 +1,191 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestECUDetailsIdentification(t *testing.T) {
+	d := &ECUDetails{
+		PartNumber:   "8D0907551M",
+		Details:      []string{"2.8l V6/5V G   0002", ""},
+		Coding:       1025,
+		WorkshopCode: 54321,
+	}
+	assert.Equal(t, Identification{
+		PartNumber:      "8D0907551M",
+		Part:            PartNumber{Base: "8D0 907 551", Index: "M"},
+		Component:       "2.8l V6/5V G",
+		SoftwareVersion: "0002",
+		Coding:          1025,
+		WorkshopCode:    54321,
+		Raw:             []string{"8D0907551M", "2.8l V6/5V G   0002", ""},
+	}, d.Identification())
+
+	// part number and component in the same block, followed by the immobilizer identifier
+	d = &ECUDetails{
+		PartNumber: "1J0920806L  KOMBI+WEGFAHRSP VDO V01",
+		Details:    []string{"VWZ7Z0X1234567"},
+	}
+	assert.Equal(t, Identification{
+		PartNumber:      "1J0920806L",
+		Part:            PartNumber{Base: "1J0 920 806", Index: "L"},
+		Component:       "KOMBI+WEGFAHRSP VDO",
+		SoftwareVersion: "V01",
+		ImmobilizerID:   "VWZ7Z0X1234567",
+		Raw:             []string{"1J0920806L  KOMBI+WEGFAHRSP VDO V01", "VWZ7Z0X1234567"},
+	}, d.Identification())
+}
+
+func TestIdentificationCorpus(t *testing.T) {
+	for _, tc := range []struct {
+		lines []string
+		want  Identification
+	}{
+		{
+			lines: []string{"8D0907551M  ", "2.8l V6/5V G   0002", "   "},
+			want: Identification{
+				PartNumber: "8D0907551M", Part: PartNumber{"8D0 907 551", "M"},
+				Component: "2.8l V6/5V G", SoftwareVersion: "0002",
+			},
+		},
+		{
+			lines: []string{"06A906018AQ ", "1.8L R4/5VT     MOTR    HS D02"},
+			want: Identification{
+				PartNumber: "06A906018AQ", Part: PartNumber{"06A 906 018", "AQ"},
+				Component: "1.8L R4/5VT     MOTR    HS", SoftwareVersion: "D02",
+			},
+		},
+		{
+			lines: []string{"038906019FQ ", "1,9l R4 EDC  G000SG  2949"},
+			want: Identification{
+				PartNumber: "038906019FQ", Part: PartNumber{"038 906 019", "FQ"},
+				Component: "1,9l R4 EDC  G000SG", SoftwareVersion: "2949",
+			},
+		},
+		{
+			lines: []string{"01V927156E  ", "AG5 01V 2.8l5V RdW 5143"},
+			want: Identification{
+				PartNumber: "01V927156E", Part: PartNumber{"01V 927 156", "E"},
+				Component: "AG5 01V 2.8l5V RdW", SoftwareVersion: "5143",
+			},
+		},
+		{
+			lines: []string{"1J0907379G  ", "ABS/EDS 20 IE CAN  0001"},
+			want: Identification{
+				PartNumber: "1J0907379G", Part: PartNumber{"1J0 907 379", "G"},
+				Component: "ABS/EDS 20 IE CAN", SoftwareVersion: "0001",
+			},
+		},
+		{
+			lines: []string{"6Q0909605A  ", "05 AIRBAG VW8    006 0001"},
+			want: Identification{
+				PartNumber: "6Q0909605A", Part: PartNumber{"6Q0 909 605", "A"},
+				Component: "05 AIRBAG VW8    006", SoftwareVersion: "0001",
+			},
+		},
+		{
+			lines: []string{"1J0 920 826 A KOMBI+WEGFAHRSP VDO H03 V01", "VWZ7Z0B0123456", "WSC 01234"},
+			want: Identification{
+				PartNumber: "1J0 920 826 A", Part: PartNumber{"1J0 920 826", "A"},
+				Component: "KOMBI+WEGFAHRSP VDO", HardwareVersion: "H03", SoftwareVersion: "V01",
+				ImmobilizerID: "VWZ7Z0B0123456", DealerCode: 1234,
+			},
+		},
+		{
+			lines: []string{"1J0959799AH ", "Tuersteuergeraet   FS 0003"},
+			want: Identification{
+				PartNumber: "1J0959799AH", Part: PartNumber{"1J0 959 799", "AH"},
+				Component: "Tuersteuergeraet   FS", SoftwareVersion: "0003",
+			},
+		},
+		{
+			lines: []string{"4B0919033   ", "4B Navigation Plus 0210"},
+			want: Identification{
+				PartNumber: "4B0919033", Part: PartNumber{"4B0 919 033", ""},
+				Component: "4B Navigation Plus", SoftwareVersion: "0210",
+			},
+		},
+		{
+			lines: []string{"FAKE ECU 1.0", "line one"},
+			want: Identification{
+				PartNumber: "FAKE", Component: "ECU 1.0 line one",
+			},
+		},
+	} {
+		t.Run(tc.lines[0], func(t *testing.T) {
+			d := &ECUDetails{}
+			for _, line := range tc.lines {
+				d.add([]byte(line))
+			}
+			tc.want.Raw = append([]string{d.PartNumber}, d.Details...)
+			assert.Equal(t, tc.want, d.Identification())
+		})
+	}
+}
+
+func TestParsePartNumber(t *testing.T) {
+	for s, want := range map[string]PartNumber{
+		"8D0907551M":    {Base: "8D0 907 551", Index: "M"},
+		"8d0 907 551 m": {Base: "8D0 907 551", Index: "M"},
+		"06A-906-032HN": {Base: "06A 906 032", Index: "HN"},
+		"4B0.919.033":   {Base: "4B0 919 033"},
+		"3B0959433AAA":  {Base: "3B0 959 433", Index: "AAA"},
+	} {
+		part, err := ParsePartNumber(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, part, s)
+	}
+	assert.Equal(t, "8D0 907 551 M", PartNumber{Base: "8D0 907 551", Index: "M"}.String())
+
+	for _, s := range []string{"", "FAKE ECU 1.0", "8D0907551MMMM", "8D0 907 55"} {
+		_, err := ParsePartNumber(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetIdentification, []byte{})
+	for _, data := range [][]byte{
+		[]byte("06A906032HN "),
+		[]byte("1.8L R4/5VT     G   0004"),
+		encodeCoding(4, 12345),
+		[]byte("AUZ7Z0X1234567"),
+	} {
+		ecuSendBytes(m, &counter, BlockTypeASCII, data)
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	}
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	var id Identification
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		id, err = c.Identify(context.Background())
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Identification{
+		PartNumber:      "06A906032HN",
+		Part:            PartNumber{Base: "06A 906 032", Index: "HN"},
+		Component:       "1.8L R4/5VT     G",
+		SoftwareVersion: "0004",
+		Coding:          4,
+		WorkshopCode:    12345,
+		ImmobilizerID:   "AUZ7Z0X1234567",
+		Raw: []string{
+			"06A906032HN",
+			"1.8L R4/5VT     G   0004",
+			"AUZ7Z0X1234567",
+		},
+	}, id)
+
+	details := c.ECUDetails()
+	assert.Equal(t, "06A906032HN", details.PartNumber, "ecu details are updated")
+	assert.Equal(t, []string{"1.8L R4/5VT     G   0004", "AUZ7Z0X1234567"}, details.Details)
+	assert.Equal(t, uint16(4), details.Coding)
+}
@@ -0,0 +1,51 @@
+package kw1281
+
+import "fmt"
+
+// the first byte of the sync sequence sent by the ECU after the 5 baud initialization
+const syncByte = 0x55
+
+// Keyword identifies the protocol spoken by an ECU. It is sent as two bytes following the sync byte
+// during initialization.
+type Keyword uint16
+
+const (
+	KeywordKW1281 Keyword = 1281
+	// ISO 9141-2 keywords
+	KeywordISO9141   Keyword = 1032
+	KeywordISO9141v2 Keyword = 2580
+	// KWP2000 uses a range of keywords, the low bits of which describe the header format supported
+	KeywordKWP2000First Keyword = 2000
+	KeywordKWP2000Last  Keyword = 2031
+)
+
+// ProtocolError is returned when the ECU responds to initialization with a keyword for a protocol other
+// than KW1281
+type ProtocolError struct {
+	Keyword Keyword
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("ecu uses unsupported protocol %v (keyword %d)", e.Keyword, uint16(e.Keyword))
+}
+
+// decode the keyword from the two bytes following the sync byte. Each byte carries 7 bits of the
+// keyword, least significant first, with the most significant bit used for odd parity.
+func decodeKeyword(kb1 byte, kb2 byte) (Keyword, bool) {
+	if evenParity(kb1) || evenParity(kb2) {
+		return 0, false
+	}
+	return Keyword(kb1&0x7f) | Keyword(kb2&0x7f)<<7, true
+}
+
+func (k Keyword) String() string {
+	switch {
+	case k == KeywordKW1281:
+		return "KW1281"
+	case k == KeywordISO9141 || k == KeywordISO9141v2:
+		return "ISO 9141-2"
+	case k >= KeywordKWP2000First && k <= KeywordKWP2000Last:
+		return "KWP2000"
+	}
+	return fmt.Sprintf("KW%d", uint16(k))
+}
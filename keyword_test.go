@@ -0,0 +1,33 @@
+package kw1281
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecodeKeyword(t *testing.T) {
+	testInputs := []struct {
+		KB1, KB2 byte
+		Keyword  Keyword
+		Name     string
+	}{
+		{0x01, 0x8a, KeywordKW1281, "KW1281"},
+		{0x08, 0x08, KeywordISO9141, "ISO 9141-2"},
+		{0x94, 0x94, KeywordISO9141v2, "ISO 9141-2"},
+		{0xd0, 0x8f, KeywordKWP2000First, "KWP2000"},
+		{0xef, 0x8f, KeywordKWP2000Last, "KWP2000"},
+		{0x02, 0x8a, 1282, "KW1282"},
+	}
+
+	for n, input := range testInputs {
+		keyword, ok := decodeKeyword(input.KB1, input.KB2)
+		assert.True(t, ok, "parity error for input %v", n)
+		assert.Equal(t, input.Keyword, keyword, "wrong keyword for input %v", n)
+		assert.Equal(t, input.Name, keyword.String(), "wrong name for input %v", n)
+	}
+
+	_, ok := decodeKeyword(0x81, 0x8a)
+	assert.False(t, ok, "parity error in first keyword byte not detected")
+	_, ok = decodeKeyword(0x01, 0x0a)
+	assert.False(t, ok, "parity error in second keyword byte not detected")
+}
@@ -1,12 +1,11 @@
 package kw1281
 
 import (
-	"bytes"
 	"context"
 	"github.com/jd3nn1s/serial"
 	"github.com/pkg/errors"
 	"io"
-	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -16,9 +15,19 @@ const (
 	initBaud        = 5
 	portDefaultBaud = 9600
 	minBlkLength    = 3
+	// times a block is retransmitted, or a NAK sent, in a row before giving up on the session
+	maxRetries = 3
 )
 
-var errPortBaud = errors.New("wrong serial port baud detected")
+// ErrPortBaud is returned when the sync byte sequence sent by the ECU could not be read correctly
+var ErrPortBaud = errors.New("wrong serial port baud detected")
+var errNAK = errors.New("ecu responded with nak")
+
+// a block was received but is corrupt, the ECU is sent a NAK so that it retransmits the block
+var errMalformedBlock = errors.New("malformed block")
+
+// baud rates tried in order when connecting, most common first
+var defaultBauds = []int{portDefaultBaud, 4800, 2400, 1200}
 
 var baudDelay = time.Second / initBaud
 var resetDelay = time.Millisecond * 300
@@ -34,18 +43,77 @@ type SerialPort interface {
 	io.ReadWriteCloser
 }
 
+// Address of an ECU on the K-line
+type Address byte
+
+const (
+	AddressEngine         Address = 0x01
+	AddressTransmission   Address = 0x02
+	AddressABS            Address = 0x03
+	AddressAirbag         Address = 0x15
+	AddressInstruments    Address = 0x17
+	AddressCentralLocking Address = 0x35
+)
+
 type Connection struct {
 	portConfig *serial.Config
 	port       SerialPort
+	address    Address
+	keyword    Keyword
 	counter    uint8
 	ecuDetails *ECUDetails
-	nextBlock  chan *Block
+	faultDB    *FaultDatabase
+	unitSystem UnitSystem
+	nextBlock  chan *request
+	schedule   pollSchedule
+	stats      connectionStats
 	done       chan struct{}
+	closeOnce  sync.Once
+
+	// measurement group layout selected by the part number of the ECU
+	groupMap MeasurementGroupMap
+	// when the most recent block started being sent to the ECU
+	lastSent time.Time
+
+	// state of the diagnostic session
+	stateMu     sync.Mutex
+	loggedIn    bool
+	outputTests bool
+	// the most recent adaptation value successfully tested, which is the only one that can be saved
+	testedAdaptation *Adaptation
+}
+
+// request is a block queued to be sent by the Start loop in place of an ACK. If reply is set, the
+// blocks the ECU sends in response are collected until it sends an ACK or NAK and are then delivered.
+type request struct {
+	blk   *Block
+	reply chan response
+	// if set, the response is complete once a block of this type is received
+	replyType BlockType
+	// if set, a NAK is the ECU refusing the request, e.g. a wrong login code, rather than a request to
+	// retransmit it
+	nakRefuses bool
+	blocks    []*Block
+}
+
+type response struct {
+	blocks []*Block
+	err    error
 }
 
 type ECUDetails struct {
 	PartNumber string
 	Details    []string
+	// serial port baud the ECU communicates at
+	Baud int
+	// protocol keyword sent by the ECU during initialization
+	Keyword Keyword
+	// software coding of the ECU and workshop code of the tester that last coded it
+	Coding       uint16
+	WorkshopCode uint32
+
+	// set once the coding block has been received
+	hasCoding bool
 }
 
 type Callbacks struct {
@@ -53,7 +121,29 @@ type Callbacks struct {
 	Measurement func(group MeasurementGroup, measurements []*Measurement)
 }
 
+type ConnectOptions struct {
+	// Address of the ECU to connect to, defaults to AddressEngine
+	Address Address
+	// Bauds are tried in order until the ECU responds with the expected sync byte, defaults to the
+	// commonly used rates starting with 9600. 10400 is not supported by the serial library, so the port
+	// cannot be opened at that rate and it is skipped.
+	Bauds []int
+}
+
+// Connect to the engine ECU
 func Connect(portName string) (*Connection, error) {
+	return Dial(portName, ConnectOptions{})
+}
+
+// Dial connects to the ECU described by the options
+func Dial(portName string, opts ConnectOptions) (*Connection, error) {
+	if opts.Address == 0 {
+		opts.Address = AddressEngine
+	}
+	if len(opts.Bauds) == 0 {
+		opts.Bauds = defaultBauds
+	}
+
 	c := &serial.Config{
 		Name:        portName,
 		Baud:        portDefaultBaud,
@@ -62,32 +152,57 @@ func Connect(portName string) (*Connection, error) {
 
 	conn := Connection{
 		portConfig: c,
+		address:    opts.Address,
+		faultDB:    DefaultFaultDatabase,
 		// buffer of 1
-		nextBlock: make(chan *Block, 1),
+		nextBlock: make(chan *request, 1),
 		done:      make(chan struct{}),
 	}
 
 	var err error
-	if err = conn.open(); err != nil {
-		return nil, err
+	for _, baud := range opts.Bauds {
+		c.Baud = baud
+		if err = conn.open(); err != nil {
+			log.WithError(err).Warnf("unable to open port at %d baud", baud)
+			continue
+		}
+		err = conn.init()
+		if err == nil {
+			break
+		}
+		log.WithError(err).Warnf("initialization failed at %d baud", baud)
+		conn.port.Close()
+		conn.port = nil
+		if _, ok := err.(*ProtocolError); ok {
+			// the ECU responded correctly but speaks another protocol, other bauds won't help
+			break
+		}
 	}
-
-	// TODO: try different baud rates
-
-	if err = conn.init(); err != nil {
+	if err != nil {
 		return nil, errors.Wrapf(err, "initialization sequence failed")
 	}
 
 	if conn.ecuDetails, err = conn.startupPhase(); err != nil {
 		return nil, errors.Wrapf(err, "startup phase failed")
 	}
+	conn.ecuDetails.Baud = c.Baud
+	conn.ecuDetails.Keyword = conn.keyword
+	conn.groupMap = LookupMeasurementMap(conn.ecuDetails.PartNumber)
 
 	return &conn, nil
 }
 
 // allow mocking
 var openPort = func(config *serial.Config) (SerialPort, error) {
-	return serial.OpenPort(config)
+	port, err := serial.OpenPort(config)
+	if err != nil {
+		return nil, err
+	}
+	if port == nil {
+		// the serial package returns no port and no error for rates it does not support
+		return nil, errors.Errorf("unsupported baud rate %d", config.Baud)
+	}
+	return port, nil
 }
 
 func (c *Connection) open() error {
@@ -97,6 +212,9 @@ func (c *Connection) open() error {
 		c.port = nil
 		return err
 	}
+	if c.port == nil {
+		return errors.Errorf("unable to open port %s at %d baud", c.portConfig.Name, c.portConfig.Baud)
+	}
 	err = c.port.Flush()
 	if err != nil {
 		c.port.Close()
@@ -106,10 +224,12 @@ func (c *Connection) open() error {
 }
 
 func (c *Connection) Close() error {
-	if c.done != nil {
-		close(c.done)
-		c.done = nil
-	}
+	// done stays closed so that requests made after Close fail rather than wait
+	c.closeOnce.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+	})
 	if c.port != nil {
 		return c.port.Close()
 	}
@@ -151,7 +271,7 @@ func (c *Connection) init() error {
 
 	// empty receive buffer (i.e. see if there's any values that need to be read)
 
-	log.Printf("starting initialization handshake with ECU at %d baud", initBaud)
+	log.Printf("starting initialization handshake with ECU %#x at %d baud", byte(c.address), initBaud)
 
 	if err := c.port.Flush(); err != nil {
 		return errors.Wrap(err, "unable to flush port")
@@ -166,26 +286,25 @@ func (c *Connection) init() error {
 	}
 	time.Sleep(resetDelay)
 
-	if err := c.setBit(false); err != nil {
-		return err
-	}
-	time.Sleep(baudDelay)
-
 	// send start bit
-	if err := c.setBit(true); err != nil {
+	if err := c.setBit(false); err != nil {
 		return err
 	}
 	time.Sleep(baudDelay)
 
-	// send the address of the ECU at 5 baud
-	initByte := uint8(0x01)
-	for n := 7; n >= 0; n-- {
-		if err := c.setBit(((initByte >> uint(n)) & 0x1) == 1); err != nil {
+	// send the address of the ECU at 5 baud, 7 data bits least significant first followed by odd parity
+	for n := uint(0); n < 7; n++ {
+		if err := c.setBit(((c.address >> n) & 0x1) == 1); err != nil {
 			return err
 		}
 		time.Sleep(baudDelay)
 	}
+	if err := c.setBit(evenParity(byte(c.address))); err != nil {
+		return err
+	}
+	time.Sleep(baudDelay)
 
+	// stop bit
 	c.port.SetBreakOff()
 	c.port.Flush()
 	if err := c.port.SetDtrOn(); err != nil {
@@ -202,8 +321,17 @@ func (c *Connection) init() error {
 	}
 
 	log.Debugf("received sync byte values {%#x, %#x, %#x}", buf[0], buf[1], buf[2])
-	if !bytes.Equal(buf, []byte{0x55, 0x01, 0x8a}) {
-		return errPortBaud
+	if buf[0] != syncByte {
+		return ErrPortBaud
+	}
+	keyword, ok := decodeKeyword(buf[1], buf[2])
+	if !ok {
+		// a parity error in the keyword bytes is a symptom of the wrong baud
+		return ErrPortBaud
+	}
+	c.keyword = keyword
+	if keyword != KeywordKW1281 {
+		return &ProtocolError{Keyword: keyword}
 	}
 	log.Printf("received expected sync byte sequence")
 
@@ -223,10 +351,29 @@ func (c *Connection) startupPhase() (*ECUDetails, error) {
 		Details: make([]string, 0, 3),
 	}
 
+	retries := 0
 	for {
 		blk, err := c.recvBlock()
 		if err != nil {
-			return nil, errors.Wrapf(err, "error reading block")
+			if errors.Cause(err) != errMalformedBlock || retries >= maxRetries {
+				return nil, errors.Wrapf(err, "error reading block")
+			}
+			retries++
+			log.WithError(err).Warn("received malformed block, sending nak")
+			if err := c.sendBlock(&Block{Type: BlockTypeNAK}); err != nil {
+				return nil, errors.Wrapf(err, "unable to send nak")
+			}
+			continue
+		}
+
+		if blk.Type == BlockTypeNAK {
+			// only ACKs are sent during startup, so the ECU is asking for the ACK again
+			if retries >= maxRetries {
+				return nil, errors.Errorf("ecu responded with nak %d times", retries+1)
+			}
+			retries++
+		} else {
+			retries = 0
 		}
 
 		if err := c.sendBlock(&Block{Type: BlockTypeACK}); err != nil {
@@ -234,6 +381,8 @@ func (c *Connection) startupPhase() (*ECUDetails, error) {
 		}
 
 		switch blk.Type {
+		case BlockTypeNAK:
+
 		case BlockTypeACK:
 			if len(ecuDetails.PartNumber) == 0 {
 				return nil, errors.New("did not receive part number before startup ack")
@@ -242,12 +391,7 @@ func (c *Connection) startupPhase() (*ECUDetails, error) {
 			return ecuDetails, nil
 
 		case BlockTypeASCII:
-			str := strings.TrimSpace(string(blk.Data))
-			if len(ecuDetails.PartNumber) == 0 {
-				ecuDetails.PartNumber = str
-			} else {
-				ecuDetails.Details = append(ecuDetails.Details, str)
-			}
+			ecuDetails.add(blk.Data)
 
 		default:
 			return nil, errors.Errorf("expected ascii block type but received %d", blk.Type)
@@ -297,15 +441,25 @@ func (c *Connection) recvBlock() (*Block, error) {
 	if _, err := c.port.Read(buf); err != nil {
 		return nil, errors.Wrapf(err, "unable to read block end")
 	}
+	c.counter++
 	if buf[0] != BlockEnd {
-		return nil, errors.Errorf("expecting byte %#x but received %#x", BlockEnd, buf[0])
+		return nil, errors.Wrapf(errMalformedBlock, "expecting byte %#x but received %#x", BlockEnd, buf[0])
 	}
-	c.counter++
+	if (blk.Type == BlockTypeMeasurementGroup || blk.Type == BlockTypeBasicSettings) && len(blk.Data) != 12 {
+		return nil, errors.Wrapf(errMalformedBlock, "measurement data must be 12 bytes but was %d", len(blk.Data))
+	}
+
+	blk.received = time.Now()
+	if !c.lastSent.IsZero() {
+		blk.latency = blk.received.Sub(c.lastSent)
+	}
+	c.stats.received(blk)
 
 	return blk, nil
 }
 
 func (c *Connection) sendBlock(blk *Block) error {
+	c.lastSent = time.Now()
 	log.WithFields(log.Fields{
 		"type":    blk.Type,
 		"size":    blk.Size(),
@@ -328,35 +482,80 @@ func (c *Connection) sendBlock(blk *Block) error {
 		}
 
 	}
-	return c.sendByte(BlockEnd)
+	if err := c.sendByte(BlockEnd); err != nil {
+		return err
+	}
+	c.stats.sent(blk)
+	return nil
 }
 
-func (c *Connection) Start(ctx context.Context, cb Callbacks) error {
+func (c *Connection) Start(ctx context.Context, cb Callbacks) (err error) {
 	if cb.ECUDetails != nil {
 		cb.ECUDetails(c.ecuDetails)
 	}
 	var measurementGroup MeasurementGroup
+	// group most recently requested, only used for measurements once the ECU accepts the request
+	var requestedGroup MeasurementGroup
+	groupRequested := false
+	// request that is waiting for the ECU to finish responding
+	var inflight *request
+	defer func() {
+		c.failRequests(inflight, err)
+	}()
+	// the last block sent, retransmitted if the ECU responds with a NAK
+	var lastBlk *Block
+	retries := 0
 	// as the ECU communicates at the incredible speed of 9600bps communicating a
 	// single byte at a time with ACK we use a busy loop to get data as fast as possible
 	for {
 		blk, err := c.recvBlock()
 		if err != nil {
-			return errors.Wrapf(err, "error reading block")
+			err = errors.Wrapf(err, "error reading block")
+			if errors.Cause(err) != errMalformedBlock || retries >= maxRetries {
+				return err
+			}
+			retries++
+			log.WithError(err).Warn("received malformed block, sending nak")
+			lastBlk = &Block{Type: BlockTypeNAK}
+			if err := c.sendBlock(lastBlk); err != nil {
+				return errors.Wrap(err, "unable to send nak")
+			}
+			continue
 		}
 
-		sendBlk := &Block{Type: BlockTypeACK}
-		select {
-		case sendBlk = <-c.nextBlock:
-			log.WithField("blockType", sendBlk.Type).Debug("sending non-ack block to ecu")
-		default:
-			log.Debug("sending ack block to ecu")
+		// the ECU may not have received the last block correctly. If it keeps responding with a NAK the
+		// block has been refused, e.g. an unsupported measurement group, and the session carries on
+		// without it while a request waiting for a reply fails.
+		if blk.Type == BlockTypeNAK && lastBlk != nil && (inflight == nil || !inflight.nakRefuses) {
+			if retries < maxRetries {
+				retries++
+				log.WithField("blockType", lastBlk.Type).Warn("received nak from ecu, retransmitting block")
+				if err := c.sendBlock(lastBlk); err != nil {
+					return errors.Wrapf(err, "unable to retransmit block type %v", lastBlk.Type)
+				}
+				continue
+			}
+			log.WithField("blockType", lastBlk.Type).Warn("ecu refused block")
+		}
+		retries = 0
+
+		if groupRequested {
+			// a refused request leaves the ECU sending the previous group
+			if blk.Type != BlockTypeNAK {
+				measurementGroup = requestedGroup
+			}
+			groupRequested = false
+		}
+
+		if inflight != nil && inflight.collect(blk) {
+			inflight = nil
 		}
 
 		switch blk.Type {
-		case BlockTypeMeasurementGroup:
+		case BlockTypeMeasurementGroup, BlockTypeBasicSettings:
 			// always sends measurement group blocks in response to a request therefore
 			// a received group is for the last group we sent.
-			m, err := blk.convert(measurementGroup)
+			m, err := c.convert(blk, measurementGroup)
 			if err != nil {
 				return errors.Wrapf(err, "unable to decode measuring block")
 			}
@@ -365,13 +564,41 @@ func (c *Connection) Start(ctx context.Context, cb Callbacks) error {
 			}
 		}
 
-		if sendBlk.Type == BlockTypeGetMeasurementGroup {
-			measurementGroup = MeasurementGroup(sendBlk.Data[0])
+		sendBlk := &Block{Type: BlockTypeACK}
+		var req *request
+		if inflight == nil {
+			select {
+			case req = <-c.nextBlock:
+				sendBlk = req.blk
+				log.WithField("blockType", sendBlk.Type).Debug("sending non-ack block to ecu")
+			default:
+				if group, ok := c.schedule.next(); ok {
+					sendBlk = &Block{
+						Type: BlockTypeGetMeasurementGroup,
+						Data: []byte{byte(group)},
+					}
+					log.WithField("group", group).Debug("sending scheduled measurement group request to ecu")
+				} else {
+					log.Debug("sending ack block to ecu")
+				}
+			}
+		}
+
+		if sendBlk.Type == BlockTypeGetMeasurementGroup || sendBlk.Type == BlockTypeGetBasicSettings {
+			requestedGroup = MeasurementGroup(sendBlk.Data[0])
+			groupRequested = true
 		}
+		lastBlk = sendBlk
 		if err := c.sendBlock(sendBlk); err != nil {
+			if req != nil && req.reply != nil {
+				inflight = req
+			}
 			return errors.Wrapf(err, "unable to send block type %v in response to block type %v",
 				sendBlk.Type, blk.Type)
 		}
+		if req != nil && req.reply != nil {
+			inflight = req
+		}
 
 		select {
 		case <-ctx.Done():
@@ -383,13 +610,152 @@ func (c *Connection) Start(ctx context.Context, cb Callbacks) error {
 }
 
 func (c *Connection) RequestMeasurementGroup(group MeasurementGroup) error {
+	if c.closed() {
+		return errors.New("connection closed")
+	}
 	select {
 	case <-c.done:
 		return errors.New("connection closed")
-	case c.nextBlock <- &Block{
+	case c.nextBlock <- &request{blk: &Block{
 		Type: BlockTypeGetMeasurementGroup,
 		Data: []byte{byte(group)},
-	}:
+	}}:
 		return nil
 	}
 }
+
+// ReadGroup requests a measurement group and waits for the ECU to respond with its measurements. The
+// Measurement callback is also called with the result.
+func (c *Connection) ReadGroup(ctx context.Context, group MeasurementGroup) ([]*Measurement, error) {
+	blocks, err := c.request(ctx, &request{
+		blk: &Block{
+			Type: BlockTypeGetMeasurementGroup,
+			Data: []byte{byte(group)},
+		},
+		replyType: BlockTypeMeasurementGroup,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read measurement group %d", group)
+	}
+	if len(blocks) == 0 {
+		return nil, errors.Errorf("ecu did not respond with measurement group %d", group)
+	}
+	return c.convert(blocks[len(blocks)-1], group)
+}
+
+// convert a measurement block using the layout and unit system of the connection
+func (c *Connection) convert(blk *Block, group MeasurementGroup) ([]*Measurement, error) {
+	measurements, err := blk.convertMap(c.MeasurementMap(), group)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range measurements {
+		m.MeasurementValue = m.ConvertTo(c.unitSystem)
+	}
+	return measurements, nil
+}
+
+// MeasurementMap returns the measurement group layout used for the ECU, the generic MeasurementMap if no
+// layout matches its part number. Groups missing from the layout are decoded without metrics or labels.
+func (c *Connection) MeasurementMap() MeasurementGroupMap {
+	if c.groupMap == nil {
+		return MeasurementMap
+	}
+	return c.groupMap
+}
+
+// BasicSettings requests a measurement group in basic settings mode, used for adaptations such as the
+// throttle body, and waits for the ECU to respond with its measurements. The Measurement callback is also
+// called with the result. The ECU remains in basic settings mode until another measurement group is requested, including by the poll schedule.
+func (c *Connection) BasicSettings(ctx context.Context, group MeasurementGroup) ([]*Measurement, error) {
+	blocks, err := c.request(ctx, &request{
+		blk: &Block{
+			Type: BlockTypeGetBasicSettings,
+			Data: []byte{byte(group)},
+		},
+		replyType: BlockTypeBasicSettings,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read basic settings group %d", group)
+	}
+	if len(blocks) == 0 {
+		return nil, errors.Errorf("ecu did not respond with basic settings group %d", group)
+	}
+	return c.convert(blocks[len(blocks)-1], group)
+}
+
+// failRequests answers the inflight request and any queued requests once the Start loop has ended, as
+// they will never be sent or receive a response
+func (c *Connection) failRequests(inflight *request, err error) {
+	if err == nil {
+		err = errors.New("start loop stopped")
+	}
+	if inflight != nil {
+		inflight.reply <- response{err: err}
+	}
+	for {
+		select {
+		case req := <-c.nextBlock:
+			if req.reply != nil {
+				req.reply <- response{err: err}
+			}
+		default:
+			return
+		}
+	}
+}
+
+// closed is checked before queueing a request, which could otherwise still be queued when there is room
+func (c *Connection) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// request queues a block to be sent by the Start loop and waits for the blocks the ECU sends in response.
+// Start must be running for the block to be sent, this applies to every exported method built on request.
+func (c *Connection) request(ctx context.Context, req *request) ([]*Block, error) {
+	// buffered so the Start loop never blocks on a caller that has given up
+	req.reply = make(chan response, 1)
+
+	if c.closed() {
+		return nil, errors.New("connection closed")
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, errors.New("connection closed")
+	case c.nextBlock <- req:
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, errors.New("connection closed")
+	case resp := <-req.reply:
+		return resp.blocks, resp.err
+	}
+}
+
+// collect a block received in response to the request, returning true once the response is complete
+func (r *request) collect(blk *Block) bool {
+	switch blk.Type {
+	case BlockTypeACK:
+		r.reply <- response{blocks: r.blocks}
+		return true
+	case BlockTypeNAK:
+		r.reply <- response{err: errNAK}
+		return true
+	}
+	r.blocks = append(r.blocks, blk)
+	if r.replyType != 0 && blk.Type == r.replyType {
+		r.reply <- response{blocks: r.blocks}
+		return true
+	}
+	return false
+}
@@ -6,7 +6,9 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"runtime"
 	"testing"
+	"time"
 )
 
 var byteECUDetails = [][]byte{
@@ -35,6 +37,32 @@ func ecuSendBytes(m *MockSerialPort, counter *uint8, blkType BlockType, data []b
 	m.ReadBuf.WriteByte(BlockEnd)
 }
 
+// run the Start loop until the mock data runs out while fn performs a request
+func runRequest(t *testing.T, c *Connection, cb Callbacks, fn func() error) error {
+	errs := make(chan error)
+	go func() {
+		errs <- fn()
+	}()
+	// the request must be queued before the Start loop receives the first block
+	for len(c.nextBlock) == 0 {
+		runtime.Gosched()
+	}
+	assert.Error(t, c.Start(context.Background(), cb))
+	return <-errs
+}
+
+// stage the ECU accepting a request and sending the response blocks, each of which is answered with an ACK
+func stageRequest(m *MockSerialPort, reqType BlockType, reqData []byte, responses ...*Block) {
+	counter := uint8(1)
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// echo of the request
+	ecuSendBytes(m, &counter, reqType, reqData)
+	for _, blk := range responses {
+		ecuSendBytes(m, &counter, blk.Type, blk.Data)
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	}
+}
+
 func TestRecvBlockShort(t *testing.T) {
 	c, m := connection()
 
@@ -110,6 +138,24 @@ func TestRecvBlockMissingEnd(t *testing.T) {
 
 	_, err := c.recvBlock()
 	assert.Error(t, err, "missing end block should fail")
+	assert.Equal(t, errMalformedBlock, errors.Cause(err))
+}
+
+func TestRecvBlockMeasurementLength(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{0x01, 0x30})
+	_, err := c.recvBlock()
+	assert.Equal(t, errMalformedBlock, errors.Cause(err))
+	assert.Equal(t, uint8(2), c.counter, "malformed block still counts")
+}
+
+// stage a block from the ECU with a corrupted block end
+func ecuSendMalformed(m *MockSerialPort, counter *uint8, blkType BlockType, data []byte) {
+	ecuSendBytes(m, counter, blkType, data)
+	m.ReadBuf.Truncate(m.ReadBuf.Len() - 1)
+	m.ReadBuf.WriteByte(0xde)
 }
 
 func TestRecvBlockCounterRollover(t *testing.T) {
@@ -293,6 +339,228 @@ func TestConnectClose(t *testing.T) {
 	assert.Error(t, c.Close())
 }
 
+func TestRequestAfterClose(t *testing.T) {
+	c, _ := connection()
+	c.done = make(chan struct{})
+	assert.NoError(t, c.Close())
+
+	errs := make(chan error)
+	go func() {
+		_, err := c.ReadFaults(context.Background())
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "request after close did not return")
+	}
+	assert.Error(t, c.RequestMeasurementGroup(GroupRPMCoolantTemp))
+	assert.Len(t, c.nextBlock, 0, "requests are not queued after close")
+}
+
+func TestDialAddress(t *testing.T) {
+	defer noDelays()()
+
+	for _, address := range []Address{AddressEngine, AddressABS, AddressInstruments, AddressCentralLocking} {
+		m := &MockSerialPort{}
+		oldOpenPort := openPort
+		openPort = func(config *serial.Config) (SerialPort, error) {
+			return m, nil
+		}
+
+		m.ReadBuf.Write([]byte{0x55, 0x01, 0x8a})
+		m.ReadBuf.Write([]byte{complement(0x8a)})
+		counter := uint8(1)
+		stageStartupPhaseData(m, &counter)
+
+		c, err := Dial("/dev/fakeport", ConnectOptions{Address: address})
+		openPort = oldOpenPort
+		assert.NoError(t, err)
+		assert.NotNil(t, c)
+
+		// idle, start bit, 7 data bits, parity bit, stop bit
+		assert.Len(t, m.Bits, 11)
+		assert.Equal(t, []bool{true, false}, m.Bits[:2], "idle followed by start bit")
+		var sent byte
+		for n, bit := range m.Bits[2:9] {
+			if bit {
+				sent |= 1 << uint(n)
+			}
+		}
+		assert.Equal(t, byte(address), sent, "address bits")
+		assert.Equal(t, evenParity(sent), m.Bits[9], "odd parity bit")
+		assert.True(t, m.Bits[10], "stop bit")
+
+		if address == AddressEngine {
+			assert.Equal(t, []bool{true, false, true, false, false, false, false, false, false, false, true},
+				m.Bits)
+		}
+	}
+}
+
+func TestDialBaudDetection(t *testing.T) {
+	defer noDelays()()
+
+	// the first port is opened at the wrong baud and receives garbage instead of the sync bytes
+	wrongBaud := &MockSerialPort{}
+	wrongBaud.ReadBuf.Write([]byte{0xf2, 0x00, 0x7e})
+
+	m := &MockSerialPort{}
+	m.ReadBuf.Write([]byte{0x55, 0x01, 0x8a})
+	m.ReadBuf.Write([]byte{complement(0x8a)})
+	counter := uint8(1)
+	stageStartupPhaseData(m, &counter)
+
+	var bauds []int
+	ports := []*MockSerialPort{wrongBaud, m}
+	oldOpenPort := openPort
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		bauds = append(bauds, config.Baud)
+		port := ports[0]
+		ports = ports[1:]
+		return port, nil
+	}
+	defer func() {
+		openPort = oldOpenPort
+	}()
+
+	var details *ECUDetails
+	c, err := Dial("/dev/fakeport", ConnectOptions{Bauds: []int{9600, 4800, 1200}})
+	assert.NoError(t, err)
+	// start ends once the mock data runs out
+	assert.Error(t, c.Start(context.Background(), Callbacks{
+		ECUDetails: func(d *ECUDetails) {
+			details = d
+		},
+	}))
+
+	assert.Equal(t, []int{9600, 4800}, bauds)
+	assert.True(t, wrongBaud.closed, "port opened at wrong baud is closed")
+	assert.Equal(t, 4800, details.Baud)
+	assert.Equal(t, KeywordKW1281, details.Keyword)
+}
+
+func TestDialNoBaud(t *testing.T) {
+	defer noDelays()()
+
+	opened := 0
+	oldOpenPort := openPort
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		opened++
+		m := &MockSerialPort{}
+		m.ReadBuf.Write([]byte{0xf2, 0x00, 0x7e})
+		return m, nil
+	}
+	defer func() {
+		openPort = oldOpenPort
+	}()
+
+	_, err := Dial("/dev/fakeport", ConnectOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, ErrPortBaud, errors.Cause(err))
+	assert.Equal(t, len(defaultBauds), opened, "all bauds are tried")
+}
+
+func TestDialUnsupportedBaud(t *testing.T) {
+	defer noDelays()()
+
+	_, err := openPort(&serial.Config{Name: "/dev/fakeport", Baud: 10400})
+	assert.Error(t, err)
+
+	oldOpenPort := openPort
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		return nil, nil
+	}
+	defer func() {
+		openPort = oldOpenPort
+	}()
+
+	_, err = Dial("/dev/fakeport", ConnectOptions{Bauds: []int{10400}})
+	assert.Error(t, err)
+
+	m := &MockSerialPort{}
+	m.ReadBuf.Write([]byte{0x55, 0x01, 0x8a})
+	m.ReadBuf.Write([]byte{complement(0x8a)})
+	counter := uint8(1)
+	stageStartupPhaseData(m, &counter)
+
+	var bauds []int
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		bauds = append(bauds, config.Baud)
+		if config.Baud == 10400 {
+			return nil, errors.Errorf("unsupported baud rate %d", config.Baud)
+		}
+		return m, nil
+	}
+
+	c, err := Dial("/dev/fakeport", ConnectOptions{Bauds: []int{10400, 9600}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10400, 9600}, bauds, "next baud is tried when the port fails to open")
+	assert.Equal(t, 9600, c.ECUDetails().Baud)
+}
+
+func TestDialProtocolError(t *testing.T) {
+	defer noDelays()()
+
+	opened := 0
+	oldOpenPort := openPort
+	openPort = func(config *serial.Config) (SerialPort, error) {
+		opened++
+		m := &MockSerialPort{}
+		// KWP2000 keyword
+		m.ReadBuf.Write([]byte{0x55, 0xef, 0x8f})
+		return m, nil
+	}
+	defer func() {
+		openPort = oldOpenPort
+	}()
+
+	_, err := Dial("/dev/fakeport", ConnectOptions{})
+	assert.Error(t, err)
+	protocolErr, ok := errors.Cause(err).(*ProtocolError)
+	assert.True(t, ok, "expected protocol error but was %v", err)
+	assert.Equal(t, Keyword(2031), protocolErr.Keyword)
+	assert.Equal(t, 1, opened, "other bauds are not tried")
+}
+
+func TestStartupPhaseMalformedBlock(t *testing.T) {
+	defer noDelays()()
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendMalformed(m, &counter, BlockTypeASCII, byteECUDetails[0])
+	// echo of the NAK sent to the ECU, which then retransmits the block
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeASCII, byteECUDetails[0])
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// the ECU did not receive the ACK and asks for it again
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	ecuDetails, err := c.startupPhase()
+	assert.NoError(t, err)
+	assert.Equal(t, string(byteECUDetails[0]), ecuDetails.PartNumber)
+	assert.Empty(t, ecuDetails.Details)
+}
+
+func TestStartupPhaseRetriesExceeded(t *testing.T) {
+	defer noDelays()()
+	c, m := connection()
+	counter := uint8(1)
+
+	for i := 0; i < maxRetries; i++ {
+		ecuSendMalformed(m, &counter, BlockTypeASCII, byteECUDetails[0])
+		ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	}
+	ecuSendMalformed(m, &counter, BlockTypeASCII, byteECUDetails[0])
+
+	_, err := c.startupPhase()
+	assert.Equal(t, errMalformedBlock, errors.Cause(err))
+}
+
 func TestStartCallbacks(t *testing.T) {
 	cbResults := struct {
 		ECUDetails  bool
@@ -342,13 +610,111 @@ func TestStartErrors(t *testing.T) {
 	c, m := connection()
 	counter := uint8(1)
 
-	// ECU send ACK and sends wrong response to ACK
+	// ECU send ACK and keeps sending a malformed response until the retries are exhausted
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
 	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	for i := 0; i < maxRetries; i++ {
+		ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{})
+		ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	}
 	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{})
 
 	err := c.Start(context.Background(), Callbacks{})
 	assert.Error(t, err)
-	assert.NotEqual(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, errMalformedBlock, errors.Cause(err))
+}
+
+func TestStartRetransmit(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// the measurement group request is lost and retransmitted
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{1})
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{1})
+	// the measurement block is corrupted and the ECU is asked to send it again
+	ecuSendMalformed(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30})
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	measurements := 0
+	c.RequestMeasurementGroup(1)
+	err := c.Start(context.Background(), Callbacks{
+		Measurement: func(group MeasurementGroup, m []*Measurement) {
+			measurements++
+		},
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, 1, measurements)
+}
+
+func TestStartRetransmitRefused(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{7})
+	// the ECU does not support the group and refuses every retransmission
+	for i := 0; i < maxRetries; i++ {
+		ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+		ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{7})
+	}
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	// the session carries on with an ACK
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	c.RequestMeasurementGroup(7)
+	err := c.Start(context.Background(), Callbacks{})
+	assert.Equal(t, io.EOF, errors.Cause(err), "session only ends when data runs out")
+}
+
+func TestStartRefusedGroupKeepsPrevious(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+	data := []byte{
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30}
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{1})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, data)
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{7})
+	for i := 0; i < maxRetries; i++ {
+		ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+		ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{7})
+	}
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// the ECU keeps sending the group it was sending before the refused request
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, data)
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	var groups []MeasurementGroup
+	c.nextBlock <- &request{blk: &Block{Type: BlockTypeGetMeasurementGroup, Data: []byte{1}}}
+	err := c.Start(context.Background(), Callbacks{
+		Measurement: func(group MeasurementGroup, m []*Measurement) {
+			groups = append(groups, group)
+			if len(groups) == 1 {
+				c.nextBlock <- &request{blk: &Block{Type: BlockTypeGetMeasurementGroup, Data: []byte{7}}}
+			}
+		},
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err))
+	assert.Equal(t, []MeasurementGroup{1, 1}, groups)
 }
 
 func TestStartSendRequest(t *testing.T) {
@@ -401,3 +767,239 @@ func TestStartSendRequest(t *testing.T) {
 	assert.Equal(t, byte(0x4), buf[3], "group is incorrect")
 	assert.Equal(t, byte(BlockEnd), buf[4])
 }
+
+func TestReadGroup(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	// ECU send ACK on start
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// echo back request for measurement group
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	// ECU send measurements and get ACK response
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0xc8, 0x31,
+		0x05, 0x07, 0xd4,
+		0x01, 0xc8, 0x31,
+		0x01, 0xc8, 0x31})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	callbacks := 0
+	cb := Callbacks{
+		Measurement: func(group MeasurementGroup, measurements []*Measurement) {
+			callbacks++
+		},
+	}
+	var measurements []*Measurement
+	err := runRequest(t, c, cb, func() (err error) {
+		measurements, err = c.ReadGroup(context.Background(), GroupRPMCoolantTemp)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Len(t, measurements, 4)
+	assert.Equal(t, MetricRPM, measurements[0].Metric)
+	assert.Equal(t, 392, measurements[0].Value)
+	assert.Equal(t, MetricCoolantTemp, measurements[1].Metric)
+	assert.Equal(t, 78.4, measurements[1].Value)
+	assert.Equal(t, 1, callbacks, "measurement callback is also called")
+}
+
+func TestReadGroupRetransmit(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// the request is lost and retransmitted rather than failing the read
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0xc8, 0x31,
+		0x05, 0x07, 0xd4,
+		0x01, 0xc8, 0x31,
+		0x01, 0xc8, 0x31})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	var measurements []*Measurement
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		measurements, err = c.ReadGroup(context.Background(), GroupRPMCoolantTemp)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Len(t, measurements, 4)
+}
+
+func TestReadGroupRefused(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{7})
+	for i := 0; i < maxRetries; i++ {
+		ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+		ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{7})
+	}
+	ecuSendBytes(m, &counter, BlockTypeNAK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		_, err := c.ReadGroup(context.Background(), 7)
+		return err
+	})
+	assert.Equal(t, errNAK, errors.Cause(err))
+}
+
+func TestReadGroupACK(t *testing.T) {
+	for blkType, read := range map[BlockType]func(c *Connection) error{
+		BlockTypeGetMeasurementGroup: func(c *Connection) error {
+			_, err := c.ReadGroup(context.Background(), GroupRPMCoolantTemp)
+			return err
+		},
+		BlockTypeGetBasicSettings: func(c *Connection) error {
+			_, err := c.BasicSettings(context.Background(), GroupRPMCoolantTemp)
+			return err
+		},
+	} {
+		c, m := connection()
+		counter := uint8(1)
+
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+		ecuSendBytes(m, &counter, blkType, []byte{byte(GroupRPMCoolantTemp)})
+		// ECU responds with an ACK rather than measurements
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+		err := runRequest(t, c, Callbacks{}, func() error {
+			return read(c)
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "did not respond")
+	}
+}
+
+func TestReadGroupTimeout(t *testing.T) {
+	c, _ := connection()
+
+	// nothing is running the Start loop so the request is never answered
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.ReadGroup(ctx, GroupRPMCoolantTemp)
+	assert.Error(t, err)
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+}
+
+func TestStartUnknownFormula(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	// second cell uses a formula that isn't known
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0xc8, 0x31,
+		0xfe, 0x12, 0x34,
+		0x01, 0xc8, 0x31,
+		0x01, 0xc8, 0x31})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0xc8, 0x31,
+		0xfe, 0x12, 0x34,
+		0x01, 0xc8, 0x31,
+		0x01, 0xc8, 0x31})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	c.RequestMeasurementGroup(GroupRPMCoolantTemp)
+	blocks := 0
+	err := c.Start(context.Background(), Callbacks{
+		Measurement: func(group MeasurementGroup, measurements []*Measurement) {
+			blocks++
+			assert.False(t, measurements[0].Undecoded)
+			assert.Equal(t, 392, measurements[0].Value)
+			assert.True(t, measurements[1].Undecoded)
+			assert.Equal(t, []byte{0xfe, 0x12, 0x34}, measurements[1].Value)
+		},
+	})
+	assert.Equal(t, io.EOF, errors.Cause(err), "session only ends when data runs out")
+	assert.Equal(t, 2, blocks)
+}
+
+func TestBasicSettings(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// echo back request for basic settings
+	ecuSendBytes(m, &counter, BlockTypeGetBasicSettings, []byte{byte(GroupRPMThrottleIntakeAirBlockNum)})
+	// ECU repeats basic settings measurements while ACKs are sent
+	for i := 0; i < 2; i++ {
+		ecuSendBytes(m, &counter, BlockTypeBasicSettings, []byte{
+			0x01, 0xc8, 0x31,
+			0x01, 0xc8, 0x31,
+			0x03, 0xd0, 0x1b,
+			0x05, 0x07, 0xd4})
+		ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	}
+
+	callbacks := 0
+	cb := Callbacks{
+		Measurement: func(group MeasurementGroup, measurements []*Measurement) {
+			callbacks++
+			assert.Equal(t, GroupRPMThrottleIntakeAirBlockNum, group)
+			assert.True(t, measurements[0].BasicSettings, "measurements not flagged as basic settings")
+		},
+	}
+	var measurements []*Measurement
+	err := runRequest(t, c, cb, func() (err error) {
+		measurements, err = c.BasicSettings(context.Background(), GroupRPMThrottleIntakeAirBlockNum)
+		return err
+	})
+	assert.Equal(t, 2, callbacks)
+
+	assert.NoError(t, err)
+	assert.Len(t, measurements, 4)
+	assert.True(t, measurements[2].BasicSettings)
+	assert.Equal(t, MetricThrottleAngle, measurements[2].Metric)
+	assert.Equal(t, 11.232000000000001, measurements[2].Value)
+
+	// check the request sent to the ECU follows the ACK of the ECU's first block
+	buf := make([]byte, 32)
+	_, err = m.WriteBuf.Read(buf)
+	assert.NoError(t, err)
+	buf = buf[minBlkLength:]
+	assert.Equal(t, byte(4), buf[0])
+	assert.Equal(t, byte(BlockTypeGetBasicSettings), buf[2])
+	assert.Equal(t, byte(GroupRPMThrottleIntakeAirBlockNum), buf[3])
+}
+
+func TestStartStopFailsRequests(t *testing.T) {
+	// the request is sent and in flight when the context ends the Start loop
+	c, m := connection()
+	counter := uint8(1)
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetErrors, []byte{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs := make(chan error)
+	go func() {
+		_, err := c.ReadFaults(context.Background())
+		errs <- err
+	}()
+	for len(c.nextBlock) == 0 {
+		runtime.Gosched()
+	}
+	assert.NoError(t, c.Start(ctx, Callbacks{}))
+	assert.Error(t, <-errs)
+
+	// the request is still queued when reading the first block fails
+	c, _ = connection()
+	go func() {
+		_, err := c.ReadFaults(context.Background())
+		errs <- err
+	}()
+	for len(c.nextBlock) == 0 {
+		runtime.Gosched()
+	}
+	assert.Error(t, c.Start(context.Background(), Callbacks{}))
+	assert.Error(t, <-errs)
+	assert.Empty(t, c.nextBlock)
+}
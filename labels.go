@@ -0,0 +1,93 @@
+package kw1281
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// label names commonly used for known metrics, in addition to the names in the metric catalogue
+var labelMetrics = map[string]Metric{
+	"supply voltage":           MetricBatteryVoltage,
+	"injection period":         MetricInjectionTime,
+	"throttle valve angle":     MetricThrottleAngle,
+	"road speed":               MetricSpeed,
+	"engine load":              MetricEngineLoad,
+	"ignition timing angle":    MetricIgnitionTiming,
+	"mass air flow":            MetricAirMass,
+	"intake manifold pressure": MetricIntakePressure,
+}
+
+func metricForLabel(name string) Metric {
+	if m, ok := labelMetrics[strings.ToLower(name)]; ok {
+		return m
+	}
+	m, _ := lookupMetricName(name)
+	return m
+}
+
+// LoadLabelFile parses a label file and registers the measurement group layout it describes for ECUs with
+// the part number, see RegisterMeasurementMap.
+func LoadLabelFile(partNumber string, r io.Reader) error {
+	m, err := ParseLabelFile(r)
+	if err != nil {
+		return err
+	}
+	RegisterMeasurementMap(partNumber, m)
+	return nil
+}
+
+// ParseLabelFile reads the measurement group layout of an ECU from a label file. Each line names a cell of a
+// measurement group, with cell 0 naming the group itself:
+//
+//	; comment
+//	001,0,Idle Speed
+//	001,1,Engine Speed,(800...880 RPM)
+//	001,2,Coolant Temperature,(80...105 C)
+//
+// Lines for other sections of the file, which start with a letter such as adaptation channels, are ignored.
+// Cells are given a Metric if their name is that of a metric in the catalogue, including those registered
+// with RegisterMetric.
+func ParseLabelFile(r io.Reader) (MeasurementGroupMap, error) {
+	m := make(MeasurementGroupMap)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") || unicode.IsLetter(rune(text[0])) {
+			continue
+		}
+
+		fields := strings.SplitN(text, ",", 4)
+		if len(fields) < 3 {
+			return nil, errors.Errorf("expected group, cell and name on line %d", line)
+		}
+		group, err := strconv.ParseUint(strings.TrimSpace(fields[0]), 10, 8)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid group on line %d", line)
+		}
+		cell, err := strconv.ParseUint(strings.TrimSpace(fields[1]), 10, 8)
+		if err != nil || cell > 4 {
+			return nil, errors.Errorf("invalid cell %q on line %d", fields[1], line)
+		}
+		label := Label{Name: strings.TrimSpace(fields[2])}
+		if len(fields) == 4 {
+			label.Description = strings.TrimSpace(fields[3])
+		}
+
+		metrics := m[MeasurementGroup(group)]
+		if cell == 0 {
+			metrics.Name = label.Name
+		} else {
+			metrics.Labels[cell-1] = label
+			metrics.Metric[cell-1] = metricForLabel(label.Name)
+		}
+		m[MeasurementGroup(group)] = metrics
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read label file")
+	}
+	return m, nil
+}
@@ -0,0 +1,76 @@
+package kw1281
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testLabelFile = `; VAG-style label file for a test ECU
+; Measuring blocks
+
+001,0,Idle Speed
+001,1,Engine Speed,(800...880 RPM)
+001,2,Coolant Temperature,(80...105 C)
+001,3,Lambda Control, (-10...10 %, varies)
+001,4,Basic Setting Conditions
+
+003,1,Engine Speed
+003,3,Throttle Valve Angle
+
+; adaptation channels are ignored
+A01,Idle Speed Adaptation
+`
+
+func TestParseLabelFile(t *testing.T) {
+	m, err := ParseLabelFile(strings.NewReader(testLabelFile))
+	assert.NoError(t, err)
+	assert.Len(t, m, 2)
+
+	group := m[GroupRPMCoolantTemp]
+	assert.Equal(t, "Idle Speed", group.Name)
+	assert.Equal(t, [4]Metric{MetricRPM, MetricCoolantTemp, 0, 0}, group.Metric)
+	assert.Equal(t, Label{Name: "Engine Speed", Description: "(800...880 RPM)"}, group.Labels[0])
+	assert.Equal(t, Label{Name: "Lambda Control", Description: "(-10...10 %, varies)"}, group.Labels[2])
+	assert.Equal(t, Label{Name: "Basic Setting Conditions"}, group.Labels[3])
+
+	group = m[GroupRPMThrottleIntakeAirBlockNum]
+	assert.Empty(t, group.Name)
+	assert.Equal(t, [4]Metric{MetricRPM, 0, MetricThrottleAngle, 0}, group.Metric)
+	assert.Empty(t, group.Labels[1].Name)
+}
+
+func TestParseLabelFileErrors(t *testing.T) {
+	for _, input := range []string{
+		"001,1",
+		"-01,1,Engine Speed",
+		"256,1,Engine Speed",
+		"001,5,Engine Speed",
+		"001,x,Engine Speed",
+	} {
+		_, err := ParseLabelFile(strings.NewReader(input))
+		assert.Error(t, err, "input %q", input)
+	}
+}
+
+func TestLoadLabelFile(t *testing.T) {
+	defer registerTestMap("06A 906 032", nil)()
+	assert.NoError(t, LoadLabelFile("06A 906 032", strings.NewReader(testLabelFile)))
+
+	m := LookupMeasurementMap("06A906032HN 1.8L R4/5VT     G   0004")
+	assert.Equal(t, "Idle Speed", m[GroupRPMCoolantTemp].Name)
+
+	b := &Block{
+		Type: BlockTypeMeasurementGroup,
+		Data: []byte{
+			0x01, 0xc8, 0x31,
+			0x05, 0x07, 0xd4,
+			0x01, 0xc8, 0x31,
+			0x01, 0xc8, 0x31,
+		},
+	}
+	measurements, err := b.convertMap(m, GroupRPMCoolantTemp)
+	assert.NoError(t, err)
+	assert.Equal(t, MetricCoolantTemp, measurements[1].Metric)
+	assert.Equal(t, "Coolant Temperature", measurements[1].Label.Name)
+}
@@ -0,0 +1,56 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrLoginRequired is returned by protected operations when Login has not succeeded
+	ErrLoginRequired = errors.New("login required")
+	// ErrLoginRejected is returned when the ECU does not accept the login code
+	ErrLoginRejected = errors.New("ecu rejected login code")
+)
+
+// Login sends the login code required by protected operations such as adaptation and coding, along with
+// the workshop code of the tester.
+func (c *Connection) Login(ctx context.Context, code uint16, workshopCode uint32) error {
+	c.stateMu.Lock()
+	c.loggedIn = false
+	c.stateMu.Unlock()
+
+	_, err := c.request(ctx, &request{
+		blk: &Block{
+			Type: BlockTypeLogin,
+			Data: []byte{byte(code >> 8), byte(code),
+				byte(workshopCode >> 16), byte(workshopCode >> 8), byte(workshopCode)},
+		},
+		nakRefuses: true,
+	})
+	if errors.Cause(err) == errNAK {
+		return ErrLoginRejected
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to login")
+	}
+
+	c.stateMu.Lock()
+	c.loggedIn = true
+	c.stateMu.Unlock()
+	return nil
+}
+
+// LoggedIn returns true if the ECU has accepted a login code
+func (c *Connection) LoggedIn() bool {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.loggedIn
+}
+
+// fail early for protected operations rather than waiting for the ECU to reject them
+func (c *Connection) requireLogin() error {
+	if !c.LoggedIn() {
+		return ErrLoginRequired
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// login code 12345 and workshop code 54321
+var loginData = []byte{0x30, 0x39, 0x00, 0xd4, 0x31}
+
+func TestLogin(t *testing.T) {
+	c, m := connection()
+	assert.False(t, c.LoggedIn())
+	stageRequest(m, BlockTypeLogin, loginData, &Block{Type: BlockTypeACK})
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		return c.Login(context.Background(), 12345, 54321)
+	})
+	assert.NoError(t, err)
+	assert.True(t, c.LoggedIn())
+}
+
+func TestLoginRejected(t *testing.T) {
+	c, m := connection()
+	c.loggedIn = true
+	stageRequest(m, BlockTypeLogin, loginData, &Block{Type: BlockTypeNAK})
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		return c.Login(context.Background(), 12345, 54321)
+	})
+	assert.Equal(t, ErrLoginRejected, err)
+	assert.False(t, c.LoggedIn(), "failed login clears the previous login")
+}
@@ -0,0 +1,114 @@
+package kw1281
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+)
+
+// MetricInfo describes what a Metric measures
+type MetricInfo struct {
+	// ID is a stable identifier, e.g. "engine.rpm"
+	ID   string
+	Name string
+	// Units the metric is decoded in by the ECU
+	Units string
+	// expected range of values
+	Min float64
+	Max float64
+}
+
+var metrics = struct {
+	sync.RWMutex
+	info map[Metric]MetricInfo
+	ids  map[string]Metric
+	next Metric
+}{
+	info: map[Metric]MetricInfo{
+		MetricRPM:             {"engine.rpm", "Engine Speed", "RPM", 0, 8000},
+		MetricCoolantTemp:     {"engine.coolant_temperature", "Coolant Temperature", "C", -40, 150},
+		MetricBatteryVoltage:  {"electrical.battery_voltage", "Battery Voltage", "V", 0, 18},
+		MetricInjectionTime:   {"fuel.injection_time", "Injection Time", "ms", 0, 30},
+		MetricThrottleAngle:   {"engine.throttle_angle", "Throttle Angle", "Deg", 0, 90},
+		MetricAirIntakeTemp:   {"engine.intake_air_temperature", "Intake Air Temperature", "C", -40, 100},
+		MetricSpeed:           {"vehicle.speed", "Vehicle Speed", "km/h", 0, 300},
+		MetricEngineLoad:      {"engine.load", "Engine Load", "%", 0, 100},
+		MetricIgnitionTiming:  {"engine.ignition_timing", "Ignition Timing", "Deg", -30, 60},
+		MetricLambda:          {"fuel.lambda", "Lambda", "-", 0.5, 1.5},
+		MetricAirMass:         {"engine.air_mass", "Air Mass", "g/s", 0, 400},
+		MetricOilTemp:         {"engine.oil_temperature", "Oil Temperature", "C", -40, 160},
+		MetricIntakePressure:  {"engine.intake_pressure", "Intake Manifold Pressure", "mbar", 0, 3000},
+		MetricFuelConsumption: {"fuel.consumption", "Fuel Consumption", "l/h", 0, 50},
+	},
+	next: metricFirstRegistered,
+}
+
+func init() {
+	metrics.ids = make(map[string]Metric, len(metrics.info))
+	for m, info := range metrics.info {
+		metrics.ids[info.ID] = m
+	}
+}
+
+// RegisterMetric adds a metric to the catalogue, e.g. for a channel of a particular ECU. The ID must not
+// already be registered.
+func RegisterMetric(info MetricInfo) (Metric, error) {
+	if info.ID == "" {
+		return 0, errors.New("metric must have an id")
+	}
+
+	metrics.Lock()
+	defer metrics.Unlock()
+	if _, ok := metrics.ids[info.ID]; ok {
+		return 0, errors.Errorf("metric %q is already registered", info.ID)
+	}
+	m := metrics.next
+	metrics.next++
+	metrics.info[m] = info
+	metrics.ids[info.ID] = m
+	return m, nil
+}
+
+// LookupMetric returns the metric with the identifier
+func LookupMetric(id string) (Metric, bool) {
+	metrics.RLock()
+	defer metrics.RUnlock()
+	m, ok := metrics.ids[id]
+	return m, ok
+}
+
+// lookup a metric by its human readable name, ignoring case. If metrics share a name the first registered
+// is used, so built-in metrics take precedence.
+func lookupMetricName(name string) (Metric, bool) {
+	metrics.RLock()
+	defer metrics.RUnlock()
+	found := Metric(0)
+	for m, info := range metrics.info {
+		if strings.EqualFold(info.Name, name) && (found == 0 || m < found) {
+			found = m
+		}
+	}
+	return found, found != 0
+}
+
+// Info returns the description of the metric, or false if it isn't in the catalogue
+func (m Metric) Info() (MetricInfo, bool) {
+	metrics.RLock()
+	defer metrics.RUnlock()
+	info, ok := metrics.info[m]
+	return info, ok
+}
+
+// String returns the stable identifier of the metric
+func (m Metric) String() string {
+	if info, ok := m.Info(); ok {
+		return info.ID
+	}
+	return fmt.Sprintf("metric.%d", int(m))
+}
+
+// InRange returns true if the value is within the expected range of the metric
+func (i MetricInfo) InRange(value float64) bool {
+	return value >= i.Min && value <= i.Max
+}
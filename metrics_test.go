@@ -0,0 +1,81 @@
+package kw1281
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMetricCatalogue(t *testing.T) {
+	for m := MetricRPM; m < metricFirstRegistered; m++ {
+		info, ok := m.Info()
+		assert.True(t, ok, "metric %d is missing from the catalogue", int(m))
+		assert.NotEmpty(t, info.ID)
+		assert.NotEmpty(t, info.Name)
+		assert.NotEmpty(t, info.Units)
+		assert.True(t, info.Min < info.Max, "metric %v has no range", m)
+
+		found, ok := LookupMetric(info.ID)
+		assert.True(t, ok)
+		assert.Equal(t, m, found)
+	}
+
+	assert.Equal(t, "engine.rpm", MetricRPM.String())
+	info, _ := MetricCoolantTemp.Info()
+	assert.Equal(t, "Coolant Temperature", info.Name)
+	assert.Equal(t, "C", info.Units)
+	assert.True(t, info.InRange(90))
+	assert.False(t, info.InRange(200))
+
+	_, ok := Metric(0).Info()
+	assert.False(t, ok)
+	assert.Equal(t, "metric.0", Metric(0).String())
+}
+
+func registerTestMetric(t *testing.T, info MetricInfo) (Metric, func()) {
+	m, err := RegisterMetric(info)
+	assert.NoError(t, err)
+	return m, func() {
+		metrics.Lock()
+		defer metrics.Unlock()
+		delete(metrics.info, m)
+		delete(metrics.ids, info.ID)
+	}
+}
+
+func TestRegisterMetric(t *testing.T) {
+	info := MetricInfo{
+		ID:    "test.boost_pressure",
+		Name:  "Boost Pressure Actual",
+		Units: "mbar",
+		Min:   0,
+		Max:   2500,
+	}
+	m, unregister := registerTestMetric(t, info)
+	defer unregister()
+	assert.True(t, m >= metricFirstRegistered)
+	assert.Equal(t, "test.boost_pressure", m.String())
+
+	found, ok := LookupMetric("test.boost_pressure")
+	assert.True(t, ok)
+	assert.Equal(t, m, found)
+
+	_, err := RegisterMetric(info)
+	assert.Error(t, err, "duplicate id should fail")
+	_, err = RegisterMetric(MetricInfo{Name: "No ID"})
+	assert.Error(t, err, "missing id should fail")
+
+	// registered metrics are recognised by name in label files
+	groups, err := ParseLabelFile(strings.NewReader("011,2,Boost Pressure Actual"))
+	assert.NoError(t, err)
+	assert.Equal(t, m, groups[11].Metric[1])
+}
+
+func TestLookupMetricNameDuplicate(t *testing.T) {
+	_, unregister := registerTestMetric(t, MetricInfo{ID: "test.rpm", Name: "Engine Speed"})
+	defer unregister()
+
+	m, ok := lookupMetricName("engine speed")
+	assert.True(t, ok)
+	assert.Equal(t, MetricRPM, m, "built-in metric takes precedence")
+}
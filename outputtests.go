@@ -0,0 +1,90 @@
+package kw1281
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// ErrOutputTestsComplete is returned when the ECU has stepped through all of its actuators
+var ErrOutputTestsComplete = errors.New("output tests complete")
+
+// OutputTest identifies the actuator being driven by the ECU during an output test
+type OutputTest struct {
+	Code uint16
+	// filled in from the fault database of the connection, empty if the code is unknown
+	Description string
+}
+
+func (o OutputTest) String() string {
+	if o.Description == "" {
+		return fmt.Sprintf("%05d", o.Code)
+	}
+	return fmt.Sprintf("%05d %s", o.Code, o.Description)
+}
+
+// StartOutputTests starts the output test sequence, returning the first actuator driven by the ECU.
+func (c *Connection) StartOutputTests(ctx context.Context) (OutputTest, error) {
+	test, err := c.outputTest(ctx)
+	if err != nil {
+		return OutputTest{}, err
+	}
+	c.stateMu.Lock()
+	c.outputTests = true
+	c.stateMu.Unlock()
+	return test, nil
+}
+
+// NextOutputTest advances the output test sequence to the next actuator. ErrOutputTestsComplete is returned
+// once every actuator has been tested.
+func (c *Connection) NextOutputTest(ctx context.Context) (OutputTest, error) {
+	c.stateMu.Lock()
+	active := c.outputTests
+	c.stateMu.Unlock()
+	if !active {
+		return OutputTest{}, errors.New("output tests have not been started")
+	}
+	return c.outputTest(ctx)
+}
+
+// StopOutputTests ends the output test sequence before every actuator has been tested. The ECU stops driving
+// the actuator once it receives any other request, so the identification is requested as it has no side
+// effects. BlockTypeEndOutput is not used as it ends the whole diagnostic session.
+func (c *Connection) StopOutputTests(ctx context.Context) error {
+	c.stateMu.Lock()
+	c.outputTests = false
+	c.stateMu.Unlock()
+
+	if _, err := c.request(ctx, &request{blk: &Block{Type: BlockTypeGetIdentification}}); err != nil {
+		return errors.Wrap(err, "unable to stop output tests")
+	}
+	return nil
+}
+
+func (c *Connection) outputTest(ctx context.Context) (OutputTest, error) {
+	blocks, err := c.request(ctx, &request{
+		blk:       &Block{Type: BlockTypeOutputTest},
+		replyType: BlockTypeOutputTestResponse,
+	})
+	if err != nil {
+		return OutputTest{}, errors.Wrap(err, "unable to perform output test")
+	}
+	if len(blocks) == 0 {
+		// the ECU acknowledges rather than responding with an actuator at the end of the sequence
+		c.stateMu.Lock()
+		c.outputTests = false
+		c.stateMu.Unlock()
+		return OutputTest{}, ErrOutputTestsComplete
+	}
+
+	blk := blocks[len(blocks)-1]
+	if len(blk.Data) < 2 {
+		return OutputTest{}, errors.Errorf("output test response must be at least 2 bytes but was %d",
+			len(blk.Data))
+	}
+	code := uint16(blk.Data[0])<<8 | uint16(blk.Data[1])
+	return OutputTest{
+		Code:        code,
+		Description: c.FaultDatabase().Faults[code],
+	}, nil
+}
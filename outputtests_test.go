@@ -0,0 +1,104 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStartOutputTests(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeOutputTest, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeOutputTestResponse, []byte{0x04, 0xe1})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	var test OutputTest
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		test, err = c.StartOutputTests(context.Background())
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1249), test.Code)
+	assert.Equal(t, "Injector Cylinder 1 -N30-", test.Description)
+	assert.Equal(t, "01249 Injector Cylinder 1 -N30-", test.String())
+	assert.True(t, c.outputTests)
+
+	// the ECU responds with a malformed output test
+	c, m = connection()
+	stageRequest(m, BlockTypeOutputTest, []byte{},
+		&Block{Type: BlockTypeOutputTestResponse, Data: []byte{0x04}})
+	err = runRequest(t, c, Callbacks{}, func() error {
+		_, err := c.StartOutputTests(context.Background())
+		return err
+	})
+	assert.Error(t, err)
+	assert.False(t, c.outputTests, "failed start does not begin the sequence")
+	_, err = c.NextOutputTest(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNextOutputTest(t *testing.T) {
+	c, _ := connection()
+	_, err := c.NextOutputTest(context.Background())
+	assert.Error(t, err, "output tests must be started first")
+
+	c, m := connection()
+	c.outputTests = true
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeOutputTest, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeOutputTestResponse, []byte{0x30, 0x39})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	var test OutputTest
+	err = runRequest(t, c, Callbacks{}, func() (err error) {
+		test, err = c.NextOutputTest(context.Background())
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, OutputTest{Code: 12345}, test)
+	assert.Equal(t, "12345", test.String())
+}
+
+func TestOutputTestsComplete(t *testing.T) {
+	c, m := connection()
+	c.outputTests = true
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeOutputTest, []byte{})
+	// ECU acknowledges at the end of the sequence
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		_, err := c.NextOutputTest(context.Background())
+		return err
+	})
+	assert.Equal(t, ErrOutputTestsComplete, err)
+	assert.False(t, c.outputTests)
+}
+
+func TestStopOutputTests(t *testing.T) {
+	c, m := connection()
+	c.outputTests = true
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// the session is not ended, the ECU responds to the identification request that stops the actuator
+	ecuSendBytes(m, &counter, BlockTypeGetIdentification, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeASCII, []byte("06A906032HN "))
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	err := runRequest(t, c, Callbacks{}, func() error {
+		return c.StopOutputTests(context.Background())
+	})
+	assert.NoError(t, err)
+	assert.False(t, c.outputTests)
+}
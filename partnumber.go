@@ -0,0 +1,50 @@
+package kw1281
+
+import (
+	"github.com/pkg/errors"
+	"regexp"
+	"strings"
+)
+
+// vehicle/part type, main group, sub group and optional index, e.g. "8D0 907 551 M". The components may be
+// separated by spaces, dashes or dots.
+var partNumberPattern = regexp.MustCompile(`^([0-9A-Z]{3})[ .-]?([0-9]{3})[ .-]?([0-9]{3})[ .-]?([A-Z]{0,3})(?:\s|$)`)
+
+// PartNumber is a VAG part number split into its components
+type PartNumber struct {
+	// e.g. "8D0 907 551"
+	Base string
+	// revision of the part, e.g. "M". Empty for the first revision.
+	Index string
+}
+
+// ParsePartNumber splits a VAG part number such as "8D0907551M" into its base and index
+func ParsePartNumber(s string) (PartNumber, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	part, n, ok := parsePartNumberPrefix(s)
+	if !ok || n != len(s) {
+		return PartNumber{}, errors.Errorf("invalid part number %q", s)
+	}
+	return part, nil
+}
+
+// parsePartNumberPrefix parses the part number at the start of s and returns the number of bytes it used
+func parsePartNumberPrefix(s string) (PartNumber, int, bool) {
+	m := partNumberPattern.FindStringSubmatchIndex(s)
+	if m == nil {
+		return PartNumber{}, 0, false
+	}
+	part := PartNumber{
+		Base:  s[m[2]:m[3]] + " " + s[m[4]:m[5]] + " " + s[m[6]:m[7]],
+		Index: s[m[8]:m[9]],
+	}
+	// the trailing whitespace is not part of the part number
+	return part, len(strings.TrimRight(s[:m[1]], " \t")), true
+}
+
+func (p PartNumber) String() string {
+	if p.Index == "" {
+		return p.Base
+	}
+	return p.Base + " " + p.Index
+}
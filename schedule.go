@@ -0,0 +1,49 @@
+package kw1281
+
+import "sync"
+
+// PollGroup is a measurement group that is read repeatedly by the poll schedule
+type PollGroup struct {
+	Group MeasurementGroup
+	// the group is read every Interval cycles through the schedule, 0 or 1 reads it every cycle
+	Interval int
+}
+
+// pollSchedule cycles through measurement groups in the order they were configured
+type pollSchedule struct {
+	mu     sync.Mutex
+	groups []PollGroup
+	cycle  int
+	pos    int
+}
+
+// SetPollSchedule configures the Start loop to continuously request the given measurement groups whenever
+// no other request is pending. Groups are read in the order given, and calling it with no groups stops
+// polling.
+func (c *Connection) SetPollSchedule(groups ...PollGroup) {
+	c.schedule.mu.Lock()
+	defer c.schedule.mu.Unlock()
+	c.schedule.groups = append([]PollGroup(nil), groups...)
+	c.schedule.cycle = 0
+	c.schedule.pos = 0
+}
+
+// next returns the next group due to be read, or false if the schedule is empty
+func (s *pollSchedule) next() (MeasurementGroup, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.groups) == 0 {
+		return 0, false
+	}
+	for {
+		for s.pos < len(s.groups) {
+			g := s.groups[s.pos]
+			s.pos++
+			if g.Interval <= 1 || s.cycle%g.Interval == 0 {
+				return g.Group, true
+			}
+		}
+		s.pos = 0
+		s.cycle++
+	}
+}
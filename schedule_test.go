@@ -0,0 +1,89 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPollScheduleNext(t *testing.T) {
+	c, _ := connection()
+
+	_, ok := c.schedule.next()
+	assert.False(t, ok, "empty schedule has no groups")
+
+	c.SetPollSchedule(
+		PollGroup{Group: GroupRPMCoolantTemp},
+		PollGroup{Group: GroupRPMThrottleIntakeAirBlockNum, Interval: 3},
+		PollGroup{Group: GroupRPMSpeedBlockNum, Interval: 2},
+	)
+
+	var groups []MeasurementGroup
+	for i := 0; i < 10; i++ {
+		group, ok := c.schedule.next()
+		assert.True(t, ok)
+		groups = append(groups, group)
+	}
+	assert.Equal(t, []MeasurementGroup{
+		// cycle 0
+		GroupRPMCoolantTemp, GroupRPMThrottleIntakeAirBlockNum, GroupRPMSpeedBlockNum,
+		// cycle 1
+		GroupRPMCoolantTemp,
+		// cycle 2
+		GroupRPMCoolantTemp, GroupRPMSpeedBlockNum,
+		// cycle 3
+		GroupRPMCoolantTemp, GroupRPMThrottleIntakeAirBlockNum,
+		// cycle 4
+		GroupRPMCoolantTemp, GroupRPMSpeedBlockNum,
+	}, groups)
+
+	c.SetPollSchedule()
+	_, ok = c.schedule.next()
+	assert.False(t, ok, "schedule is cleared")
+}
+
+func TestPollScheduleSkipsEmptyCycles(t *testing.T) {
+	c, _ := connection()
+	c.SetPollSchedule(PollGroup{Group: GroupRPMSpeedBlockNum, Interval: 5})
+
+	for i := 0; i < 3; i++ {
+		group, ok := c.schedule.next()
+		assert.True(t, ok)
+		assert.Equal(t, GroupRPMSpeedBlockNum, group)
+	}
+	assert.Equal(t, 10, c.schedule.cycle)
+}
+
+func TestStartPollSchedule(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	measurementData := []byte{
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30}
+
+	// ECU send ACK on start
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	// each scheduled request is answered with measurements
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, measurementData)
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMSpeedBlockNum)})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, measurementData)
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, measurementData)
+
+	c.SetPollSchedule(
+		PollGroup{Group: GroupRPMCoolantTemp},
+		PollGroup{Group: GroupRPMSpeedBlockNum, Interval: 2},
+	)
+
+	var groups []MeasurementGroup
+	assert.Error(t, c.Start(context.Background(), Callbacks{
+		Measurement: func(group MeasurementGroup, measurements []*Measurement) {
+			groups = append(groups, group)
+		},
+	}))
+	assert.Equal(t, []MeasurementGroup{GroupRPMCoolantTemp, GroupRPMSpeedBlockNum, GroupRPMCoolantTemp}, groups)
+}
@@ -0,0 +1,76 @@
+package kw1281
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats describes the throughput of the connection to the ECU
+type Stats struct {
+	// Blocks received from the ECU
+	Blocks uint64
+	// Bytes of blocks both received from and sent to the ECU
+	Bytes uint64
+	// Elapsed time between the first and most recent block received
+	Elapsed time.Duration
+	// round-trip time between starting to send a block and receiving the ECU's response
+	MeanLatency time.Duration
+	MaxLatency  time.Duration
+}
+
+type connectionStats struct {
+	mu           sync.Mutex
+	stats        Stats
+	first        time.Time
+	totalLatency time.Duration
+	latencies    uint64
+}
+
+// BlocksPerSecond returns the rate at which blocks have been received from the ECU
+func (s Stats) BlocksPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Blocks) / s.Elapsed.Seconds()
+}
+
+// BytesPerSecond returns the rate at which bytes have been transferred in both directions
+func (s Stats) BytesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// Stats returns the throughput and latency of the connection so far
+func (c *Connection) Stats() Stats {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+	return c.stats.stats
+}
+
+func (s *connectionStats) sent(blk *Block) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// includes block end byte
+	s.stats.Bytes += uint64(blk.Size() + 1)
+}
+
+func (s *connectionStats) received(blk *Block) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.first.IsZero() {
+		s.first = blk.received
+	}
+	s.stats.Blocks++
+	s.stats.Bytes += uint64(blk.Size() + 1)
+	s.stats.Elapsed = blk.received.Sub(s.first)
+	if blk.latency > 0 {
+		s.latencies++
+		s.totalLatency += blk.latency
+		s.stats.MeanLatency = s.totalLatency / time.Duration(s.latencies)
+		if blk.latency > s.stats.MaxLatency {
+			s.stats.MaxLatency = blk.latency
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStatsReceived(t *testing.T) {
+	c, _ := connection()
+	assert.Equal(t, Stats{}, c.Stats())
+	assert.Equal(t, float64(0), c.Stats().BlocksPerSecond())
+
+	start := time.Now()
+	c.stats.received(&Block{Type: BlockTypeACK, received: start})
+	c.stats.sent(&Block{Type: BlockTypeACK})
+	c.stats.received(&Block{
+		Type:     BlockTypeASCII,
+		Data:     []byte("abc"),
+		received: start.Add(time.Second),
+		latency:  100 * time.Millisecond,
+	})
+	c.stats.received(&Block{
+		Type:     BlockTypeACK,
+		received: start.Add(2 * time.Second),
+		latency:  300 * time.Millisecond,
+	})
+
+	s := c.Stats()
+	assert.Equal(t, uint64(3), s.Blocks)
+	assert.Equal(t, uint64(4+4+7+4), s.Bytes)
+	assert.Equal(t, 2*time.Second, s.Elapsed)
+	assert.Equal(t, 200*time.Millisecond, s.MeanLatency)
+	assert.Equal(t, 300*time.Millisecond, s.MaxLatency)
+	assert.Equal(t, 1.5, s.BlocksPerSecond())
+	assert.Equal(t, 9.5, s.BytesPerSecond())
+}
+
+func TestStartMeasurementTiming(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{1})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30,
+		0x01, 0x30, 0x30})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	before := time.Now()
+	c.RequestMeasurementGroup(1)
+	var measurements []*Measurement
+	assert.Error(t, c.Start(context.Background(), Callbacks{
+		Measurement: func(group MeasurementGroup, m []*Measurement) {
+			measurements = m
+		},
+	}))
+
+	assert.Len(t, measurements, 4)
+	for _, m := range measurements {
+		assert.False(t, m.Timestamp.Before(before), "timestamp is when the block was received")
+		assert.True(t, m.Latency > 0, "latency is measured from the measurement group request")
+		assert.Equal(t, measurements[0].Timestamp, m.Timestamp, "all measurements in a block share a timestamp")
+	}
+
+	s := c.Stats()
+	assert.Equal(t, uint64(2), s.Blocks)
+	assert.True(t, s.MaxLatency >= measurements[0].Latency)
+}
@@ -1,93 +1,294 @@
 package kw1281
 
-import "math"
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"math"
+	"sync"
+)
 
+// Formula 37 selects a text from a table that differs between ECUs and the formulas above 70 are specific
+// to ECU families, these are registered with RegisterTextFormula and RegisterFormula. Formulas that are
+// not defined are delivered as undecoded values.
 var transformationMap = map[byte]func(byte, byte) MeasurementValue{
 	0: nil,
 	1: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units: "RPM",
-			Value: int(float64(b) * 0.2 * float64(b2) * 0.2),
-		}
+		return integerValue("RPM", int(float64(b)*0.2*float64(b2)*0.2))
 	},
 	2: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units: "%",
-			Value: float64(b) * 0.002 * float64(b2),
-		}
+		return numericValue("%", float64(b)*0.002*float64(b2))
 	},
 	3: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units: "Deg",
-			Value: float64(b) * 0.002 * float64(b2),
-		}
+		return numericValue("Deg", float64(b)*0.002*float64(b2))
 	},
 	4: func(b byte, b2 byte) MeasurementValue {
 		val := float64(math.Abs(float64(b2)-127) * 0.01 * float64(b))
-		m := MeasurementValue{
-			Value: val,
-			Units: "ATDC",
-		}
+		m := numericValue("ATDC", val)
 		if val < 128 {
 			m.Units = "BTDC"
 		}
 		return m
 	},
 	5: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:    "C",
-			Value: (0.1 * float64(b) * float64(b2)) - (10 * float64(b)),
-		}
+		return numericValue("C", (0.1*float64(b)*float64(b2))-(10*float64(b)))
 	},
 	6: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:    "V",
-			Value: 0.001 * float64(b) * float64(b2),
-		}
+		return numericValue("V", 0.001*float64(b)*float64(b2))
 	},
 	7: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:  "km/h",
-			Value: int(0.01 * float64(b) * float64(b2)),
-		}
+		return integerValue("km/h", int(0.01*float64(b)*float64(b2)))
 	},
 	8: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:    "-",
-			Value: 0.1 * float64(b) * float64(b2),
-		}
+		return numericValue("-", 0.1*float64(b)*float64(b2))
 	},
 	9: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:    "Deg",
-			Value: float64(b2-127) * 0.02 * float64(b),
-		}
+		return numericValue("Deg", float64(b2-127)*0.02*float64(b))
 	},
 	10: func(b byte, b2 byte) MeasurementValue {
-		m := MeasurementValue{
-			Value: "WARM",
-		}
 		if b == 0 {
-			m.Value = "COLD"
+			return textValue("", "COLD")
 		}
-		return m
+		return textValue("", "WARM")
 	},
 	11: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:    "-",
-			Value: 0.0001*float64(b)*float64(b2-128) + 1,
-		}
+		return numericValue("-", 0.0001*float64(b)*float64(b2-128)+1)
+	},
+	12: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("Ohm", 0.001*float64(b)*float64(b2))
+	},
+	13: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("mm", (float64(b2)-127)*0.001*float64(b))
+	},
+	14: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("bar", 0.005*float64(b)*float64(b2))
 	},
 	15: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:  "ms",
-			Value: int(0.01 * float64(b) * float64(b2)),
-		}
+		return integerValue("ms", int(0.01*float64(b)*float64(b2)))
 	},
 	16: func(b byte, b2 byte) MeasurementValue {
-		return MeasurementValue{
-			Units:      "-",
-			Value:    []byte{b2, b},
+		return bitfieldValue("-", []byte{b2, b})
+	},
+	17: func(b byte, b2 byte) MeasurementValue {
+		return textValue("", string([]byte{b, b2}))
+	},
+	18: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("mbar", 0.04*float64(b)*float64(b2))
+	},
+	19: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("l", 0.01*float64(b)*float64(b2))
+	},
+	20: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("%", float64(b)*(float64(b2)-128)/128)
+	},
+	21: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("V", 0.001*float64(b)*float64(b2))
+	},
+	22: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("ms", 0.001*float64(b)*float64(b2))
+	},
+	23: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("%", float64(b2)/256*float64(b))
+	},
+	24: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("A", 0.001*float64(b)*float64(b2))
+	},
+	25: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("g/s", float64(b2)*1.421+float64(b)/182)
+	},
+	26: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("C", int(b2)-int(b))
+	},
+	27: func(b byte, b2 byte) MeasurementValue {
+		m := numericValue("ATDC", math.Abs(float64(b2)-128)*0.01*float64(b))
+		if b2 < 128 {
+			m.Units = "BTDC"
+		}
+		return m
+	},
+	28: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("-", int(b2)-int(b))
+	},
+	29: func(b byte, b2 byte) MeasurementValue {
+		if b2 < b {
+			return textValue("", "1. Map")
+		}
+		return textValue("", "2. Map")
+	},
+	30: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("Deg k/w", float64(b2)/12*float64(b))
+	},
+	31: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("C", float64(b2)/2560*float64(b))
+	},
+	32: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("-", int(int8(b2)))
+	},
+	33: func(b byte, b2 byte) MeasurementValue {
+		if b == 0 {
+			return numericValue("%", 100*float64(b2))
+		}
+		return numericValue("%", 100*float64(b2)/float64(b))
+	},
+	34: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("kW", (float64(b2)-128)*0.01*float64(b))
+	},
+	35: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("l/h", 0.01*float64(b)*float64(b2))
+	},
+	36: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("km", int(b)*2560+int(b2)*10)
+	},
+	38: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("Deg k/w", (float64(b2)-128)*0.001*float64(b))
+	},
+	39: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("mg/h", float64(b2)/256*float64(b))
+	},
+	40: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("A", float64(b2)*0.1+25.5*float64(b)-400)
+	},
+	41: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("Ah", int(b2)+int(b)*255)
+	},
+	42: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("kW", float64(b2)*0.1+25.5*float64(b)-400)
+	},
+	43: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("V", float64(b2)*0.1+25.5*float64(b))
+	},
+	44: func(b byte, b2 byte) MeasurementValue {
+		return textValue("h:m", fmt.Sprintf("%02d:%02d", b, b2))
+	},
+	45: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("-", 0.1*float64(b)*float64(b2)/100)
+	},
+	46: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("Deg k/w", (float64(b)*float64(b2)-3200)*0.0027)
+	},
+	47: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("ms", (int(b2)-128)*int(b))
+	},
+	48: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("-", int(b2)+int(b)*255)
+	},
+	49: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("mg/h", float64(b2)/4*float64(b)*0.1)
+	},
+	50: func(b byte, b2 byte) MeasurementValue {
+		if b == 0 {
+			return numericValue("mbar", 0)
+		}
+		return numericValue("mbar", (float64(b2)-128)/(0.01*float64(b)))
+	},
+	51: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("mg/h", (float64(b2)-128)/255*float64(b))
+	},
+	52: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("Nm", float64(b2)*0.02*float64(b)-float64(b))
+	},
+	53: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("g/s", (float64(b2)-128)*1.4222+0.006*float64(b))
+	},
+	54: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("count", int(b)*256+int(b2))
+	},
+	55: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("s", float64(b)*float64(b2)/200)
+	},
+	56: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("WSC", int(b)*256+int(b2))
+	},
+	57: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("WSC", int(b)*256+int(b2)+65536)
+	},
+	58: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("/s", 1.0225*float64(int8(b2)))
+	},
+	59: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("g/s", (float64(b)*256+float64(b2))/32768)
+	},
+	60: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("s", (float64(b)*256+float64(b2))*0.01)
+	},
+	61: func(b byte, b2 byte) MeasurementValue {
+		if b == 0 {
+			return numericValue("-", float64(b2)-128)
 		}
+		return numericValue("-", (float64(b2)-128)/float64(b))
+	},
+	62: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("S", 0.256*float64(b)*float64(b2))
+	},
+	63: func(b byte, b2 byte) MeasurementValue {
+		return textValue("", string([]byte{b, b2})+"?")
+	},
+	64: func(b byte, b2 byte) MeasurementValue {
+		return integerValue("Ohm", int(b)+int(b2))
+	},
+	65: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("mm", 0.01*float64(b)*(float64(b2)-127))
+	},
+	66: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("V", float64(b)*float64(b2)/511.12)
+	},
+	67: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("Deg", 640*float64(b)+float64(b2)*2.5)
+	},
+	68: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("Deg/s", (256*float64(b)+float64(b2))/7.365)
+	},
+	69: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("bar", (256*float64(b)+float64(b2))*0.3254)
 	},
+	70: func(b byte, b2 byte) MeasurementValue {
+		return numericValue("m/s^2", (256*float64(b)+float64(b2))*0.192)
+	},
+}
+
+// guards formulas registered while measurements are being decoded
+var formulasMu sync.RWMutex
+
+func lookupFormula(formula byte) (func(byte, byte) MeasurementValue, bool) {
+	formulasMu.RLock()
+	defer formulasMu.RUnlock()
+	fn, ok := transformationMap[formula]
+	return fn, ok
+}
+
+func registerFormula(formula byte, fn func(byte, byte) MeasurementValue) error {
+	formulasMu.Lock()
+	defer formulasMu.Unlock()
+	if _, ok := transformationMap[formula]; ok {
+		return errors.Errorf("formula %d is already defined", formula)
+	}
+	transformationMap[formula] = fn
+	return nil
+}
+
+// RegisterFormula adds a numeric formula used by a particular ECU family, decoding the two value bytes
+// a and b into a value in units. Formulas that are already defined cannot be replaced.
+func RegisterFormula(formula byte, units string, fn func(a, b byte) float64) error {
+	if fn == nil {
+		return errors.Errorf("formula %d must have a function", formula)
+	}
+	return registerFormula(formula, func(b byte, b2 byte) MeasurementValue {
+		return numericValue(units, fn(b, b2))
+	})
+}
+
+// RegisterTextFormula adds a formula that selects a text by the two value bytes, a in the upper byte of
+// the key, such as formula 37 whose table differs between ECUs. Values missing from the table are
+// delivered undecoded. Formulas that are already defined cannot be replaced.
+func RegisterTextFormula(formula byte, texts map[uint16]string) error {
+	table := make(map[uint16]string, len(texts))
+	for k, v := range texts {
+		table[k] = v
+	}
+	return registerFormula(formula, func(b byte, b2 byte) MeasurementValue {
+		text, ok := table[uint16(b)<<8|uint16(b2)]
+		if !ok {
+			return *rawValue([3]byte{formula, b, b2})
+		}
+		return textValue("", text)
+	})
 }
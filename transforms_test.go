@@ -0,0 +1,171 @@
+package kw1281
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+func TestTransformations(t *testing.T) {
+	intType := reflect.TypeOf(int(0))
+	floatType := reflect.TypeOf(float64(0))
+	strType := reflect.TypeOf("")
+
+	testInputs := []struct {
+		Data          []byte
+		ExpectedType  reflect.Type
+		ExpectedValue interface{}
+		ExpectedUnits string
+	}{
+		{[]byte{0x0c, 0x32, 0x64}, floatType, 5.0, "Ohm"},
+		{[]byte{0x0d, 0x0a, 0x8b}, floatType, 0.12, "mm"},
+		{[]byte{0x0e, 0x14, 0x32}, floatType, 5.0, "bar"},
+		{[]byte{0x11, 0x4f, 0x4b}, strType, "OK", ""},
+		{[]byte{0x12, 0x19, 0x64}, floatType, 100.0, "mbar"},
+		{[]byte{0x13, 0x0a, 0x32}, floatType, 5.0, "l"},
+		{[]byte{0x14, 0x64, 0xa0}, floatType, 25.0, "%"},
+		{[]byte{0x15, 0x64, 0x8c}, floatType, 14.0, "V"},
+		{[]byte{0x16, 0x0a, 0x64}, floatType, 1.0, "ms"},
+		{[]byte{0x17, 0x64, 0x80}, floatType, 50.0, "%"},
+		{[]byte{0x18, 0x0a, 0xc8}, floatType, 2.0, "A"},
+		{[]byte{0x19, 0xb6, 0x0a}, floatType, 15.21, "g/s"},
+		{[]byte{0x1a, 0x28, 0x78}, intType, 80, "C"},
+		{[]byte{0x1b, 0x64, 0x8c}, floatType, 12.0, "ATDC"},
+		{[]byte{0x1b, 0x64, 0x74}, floatType, 12.0, "BTDC"},
+		{[]byte{0x1c, 0x78, 0x28}, intType, -80, "-"},
+		{[]byte{0x1d, 0x0a, 0x05}, strType, "1. Map", ""},
+		{[]byte{0x1d, 0x05, 0x0a}, strType, "2. Map", ""},
+		{[]byte{0x1e, 0x0c, 0x18}, floatType, 24.0, "Deg k/w"},
+		{[]byte{0x1f, 0x0a, 0x00}, floatType, 0.0, "C"},
+		{[]byte{0x20, 0x00, 0xfe}, intType, -2, "-"},
+		{[]byte{0x20, 0x00, 0x7f}, intType, 127, "-"},
+		{[]byte{0x21, 0x00, 0x05}, floatType, 500.0, "%"},
+		{[]byte{0x21, 0x04, 0x02}, floatType, 50.0, "%"},
+		{[]byte{0x22, 0x64, 0x96}, floatType, 22.0, "kW"},
+		{[]byte{0x23, 0x0a, 0x19}, floatType, 2.5, "l/h"},
+		{[]byte{0x24, 0x02, 0x05}, intType, 5170, "km"},
+		{[]byte{0x26, 0x64, 0x8a}, floatType, 1.0, "Deg k/w"},
+		{[]byte{0x27, 0x10, 0x40}, floatType, 4.0, "mg/h"},
+		{[]byte{0x28, 0x10, 0x64}, floatType, 18.0, "A"},
+		{[]byte{0x29, 0x01, 0x0a}, intType, 265, "Ah"},
+		{[]byte{0x2a, 0x10, 0x64}, floatType, 18.0, "kW"},
+		{[]byte{0x2b, 0x00, 0x8a}, floatType, 13.8, "V"},
+		{[]byte{0x2c, 0x0c, 0x05}, strType, "12:05", "h:m"},
+		{[]byte{0x2d, 0x0a, 0x64}, floatType, 1.0, "-"},
+		{[]byte{0x2e, 0x64, 0x40}, floatType, 8.64, "Deg k/w"},
+		{[]byte{0x2f, 0x02, 0x7e}, intType, -4, "ms"},
+		{[]byte{0x30, 0x01, 0x01}, intType, 256, "-"},
+		{[]byte{0x31, 0x0a, 0x28}, floatType, 10.0, "mg/h"},
+		{[]byte{0x32, 0x00, 0x90}, floatType, 0.0, "mbar"},
+		{[]byte{0x32, 0x0a, 0x90}, floatType, 160.0, "mbar"},
+		{[]byte{0x33, 0xff, 0x90}, floatType, 16.0, "mg/h"},
+		{[]byte{0x34, 0x0a, 0x64}, floatType, 10.0, "Nm"},
+		{[]byte{0x35, 0x64, 0x81}, floatType, 2.0221999999999998, "g/s"},
+		{[]byte{0x36, 0x01, 0x02}, intType, 258, "count"},
+		{[]byte{0x37, 0x0a, 0x14}, floatType, 1.0, "s"},
+		{[]byte{0x38, 0x30, 0x39}, intType, 12345, "WSC"},
+		{[]byte{0x39, 0x00, 0x01}, intType, 65537, "WSC"},
+		{[]byte{0x3a, 0x00, 0xfe}, floatType, -2.045, "/s"},
+		{[]byte{0x3b, 0x80, 0x00}, floatType, 1.0, "g/s"},
+		{[]byte{0x3c, 0x01, 0x00}, floatType, 2.56, "s"},
+		{[]byte{0x3d, 0x04, 0x88}, floatType, 2.0, "-"},
+		{[]byte{0x3d, 0x00, 0x88}, floatType, 8.0, "-"},
+		{[]byte{0x3e, 0x0a, 0x0a}, floatType, 25.6, "S"},
+		{[]byte{0x3f, 0x4f, 0x4b}, strType, "OK?", ""},
+		{[]byte{0x40, 0x0a, 0x14}, intType, 30, "Ohm"},
+		{[]byte{0x41, 0x0a, 0x8b}, floatType, 1.2000000000000002, "mm"},
+		{[]byte{0x42, 0x0a, 0x64}, floatType, 1.9564877132571608, "V"},
+		{[]byte{0x43, 0x01, 0x04}, floatType, 650.0, "Deg"},
+		{[]byte{0x44, 0x00, 0x49}, floatType, 9.911744738628649, "Deg/s"},
+		{[]byte{0x45, 0x00, 0x64}, floatType, 32.54, "bar"},
+		{[]byte{0x46, 0x00, 0x0a}, floatType, 1.92, "m/s^2"},
+	}
+
+	for _, input := range testInputs {
+		m := dataToType(input.Data)
+		assert.False(t, m.Undecoded, "formula %d", input.Data[0])
+		assert.Equal(t, input.ExpectedType, reflect.TypeOf(m.Value), "wrong type for formula %d", input.Data[0])
+		if input.ExpectedType == floatType {
+			assert.InDelta(t, input.ExpectedValue, m.Value, 1e-9, "not equal for formula %d", input.Data[0])
+		} else {
+			assert.Equal(t, input.ExpectedValue, m.Value, "not equal for formula %d", input.Data[0])
+		}
+		assert.Equal(t, input.ExpectedUnits, m.Units, "wrong units for formula %d", input.Data[0])
+	}
+}
+
+func TestEmptyTransformation(t *testing.T) {
+	m := dataToType([]byte{0x00, 0x00, 0x00})
+	assert.True(t, m.Undecoded, "formula 0 has no transformation")
+
+	for _, formula := range []byte{37, 71} {
+		assert.True(t, dataToType([]byte{formula, 0x01, 0x02}).Undecoded, "formula %d is not implemented", formula)
+	}
+}
+
+func TestTransformationKinds(t *testing.T) {
+	for formula, fn := range transformationMap {
+		if fn == nil {
+			continue
+		}
+		for _, data := range [][2]byte{{0x00, 0x00}, {0x0a, 0x8b}, {0xff, 0xff}} {
+			m := DecodeValue([3]byte{formula, data[0], data[1]})
+			f, numeric := m.Float()
+			switch v := m.Value.(type) {
+			case int:
+				assert.True(t, numeric, "formula %d", formula)
+				assert.Equal(t, float64(v), f, "formula %d", formula)
+			case float64:
+				assert.True(t, numeric, "formula %d", formula)
+				assert.Equal(t, v, f, "formula %d", formula)
+			case string:
+				text, ok := m.Text()
+				assert.True(t, ok, "formula %d", formula)
+				assert.Equal(t, v, text, "formula %d", formula)
+			case []byte:
+				assert.Equal(t, KindBitfield, m.Kind(), "formula %d", formula)
+			default:
+				assert.Fail(t, "unexpected value type", "formula %d returned %T", formula, m.Value)
+			}
+		}
+	}
+}
+
+func unregisterFormula(formula byte) {
+	formulasMu.Lock()
+	defer formulasMu.Unlock()
+	delete(transformationMap, formula)
+}
+
+func TestRegisterFormula(t *testing.T) {
+	defer unregisterFormula(71)
+	assert.NoError(t, RegisterFormula(71, "kPa", func(a, b byte) float64 {
+		return float64(a) * float64(b) / 10
+	}))
+	m := DecodeValue([3]byte{71, 0x0a, 0x65})
+	assert.False(t, m.Undecoded)
+	assert.Equal(t, 101.0, m.Value)
+	assert.Equal(t, "kPa", m.Units)
+	assert.Equal(t, [3]byte{71, 0x0a, 0x65}, m.Raw)
+
+	assert.Error(t, RegisterFormula(71, "kPa", func(a, b byte) float64 { return 0 }), "already registered")
+	assert.Error(t, RegisterFormula(1, "RPM", func(a, b byte) float64 { return 0 }), "built in formulas are kept")
+	assert.Error(t, RegisterFormula(72, "kPa", nil))
+}
+
+func TestRegisterTextFormula(t *testing.T) {
+	defer unregisterFormula(37)
+	texts := map[uint16]string{0x0001: "Idle", 0x0102: "Part load"}
+	assert.NoError(t, RegisterTextFormula(37, texts))
+	texts[0x0003] = "changed after registering"
+
+	m := DecodeValue([3]byte{37, 0x01, 0x02})
+	text, ok := m.Text()
+	assert.True(t, ok)
+	assert.Equal(t, "Part load", text)
+	assert.Equal(t, "Idle", DecodeValue([3]byte{37, 0x00, 0x01}).Value)
+
+	m = DecodeValue([3]byte{37, 0x00, 0x03})
+	assert.True(t, m.Undecoded, "text missing from the table")
+	assert.Equal(t, [3]byte{37, 0x00, 0x03}, m.Raw)
+}
@@ -0,0 +1,95 @@
+package kw1281
+
+import "github.com/pkg/errors"
+
+// UnitSystem selects the units measurements are delivered in
+type UnitSystem int
+
+const (
+	// UnitSystemMetric delivers measurements in the units decoded from the ECU
+	UnitSystemMetric UnitSystem = iota
+	// UnitSystemUS converts measurements to US customary units where possible
+	UnitSystemUS
+)
+
+type unitConversion struct {
+	from, to string
+}
+
+var unitConversions = map[unitConversion]func(float64) float64{
+	{"C", "F"}:          func(v float64) float64 { return v*9/5 + 32 },
+	{"F", "C"}:          func(v float64) float64 { return (v - 32) * 5 / 9 },
+	{"km/h", "mph"}:     func(v float64) float64 { return v / 1.609344 },
+	{"mph", "km/h"}:     func(v float64) float64 { return v * 1.609344 },
+	{"km", "mi"}:        func(v float64) float64 { return v / 1.609344 },
+	{"mi", "km"}:        func(v float64) float64 { return v * 1.609344 },
+	{"mm", "in"}:        func(v float64) float64 { return v / 25.4 },
+	{"l", "gal"}:        func(v float64) float64 { return v / 3.785411784 },
+	{"l/h", "gal/h"}:    func(v float64) float64 { return v / 3.785411784 },
+	{"bar", "psi"}:      func(v float64) float64 { return v * 14.503773773 },
+	{"mbar", "psi"}:     func(v float64) float64 { return v * 0.014503773773 },
+	{"mbar", "inHg"}:    func(v float64) float64 { return v * 0.029529983 },
+	{"g/s", "lb/min"}:   func(v float64) float64 { return v * 0.132277357 },
+	{"Nm", "lb-ft"}:     func(v float64) float64 { return v * 0.737562149 },
+	{"kW", "hp"}:        func(v float64) float64 { return v * 1.341022090 },
+	{"mg/h", "oz/h"}:    func(v float64) float64 { return v / 28349.523125 },
+	{"m/s^2", "ft/s^2"}: func(v float64) float64 { return v / 0.3048 },
+}
+
+// the units each unit system prefers in place of the units decoded from the ECU
+var unitSystems = map[UnitSystem]map[string]string{
+	UnitSystemUS: {
+		"C":     "F",
+		"km/h":  "mph",
+		"km":    "mi",
+		"mm":    "in",
+		"l":     "gal",
+		"l/h":   "gal/h",
+		"bar":   "psi",
+		"mbar":  "psi",
+		"g/s":   "lb/min",
+		"Nm":    "lb-ft",
+		"kW":    "hp",
+		"mg/h":  "oz/h",
+		"m/s^2": "ft/s^2",
+	},
+}
+
+// Convert returns a copy of the value in different units. Only numeric values can be converted, and the
+// raw bytes are preserved so the original value can always be decoded again.
+func (m *MeasurementValue) Convert(units string) (*MeasurementValue, error) {
+	if m.Units == units {
+		return m, nil
+	}
+	v, ok := m.Float()
+	if !ok {
+		return nil, errors.Errorf("unable to convert %v value to %s", m.Kind(), units)
+	}
+	fn, ok := unitConversions[unitConversion{m.Units, units}]
+	if !ok {
+		return nil, errors.Errorf("no conversion from %s to %s", m.Units, units)
+	}
+	converted := numericValue(units, fn(v))
+	converted.Raw = m.Raw
+	return &converted, nil
+}
+
+// ConvertTo returns the value in the units preferred by the unit system, or the value unchanged if the
+// unit system has no preference for its units.
+func (m *MeasurementValue) ConvertTo(system UnitSystem) *MeasurementValue {
+	units, ok := unitSystems[system][m.Units]
+	if !ok {
+		return m
+	}
+	converted, err := m.Convert(units)
+	if err != nil {
+		return m
+	}
+	return converted
+}
+
+// SetUnitSystem selects the units that measurements are delivered in by Start and ReadGroup. It should be
+// called before Start.
+func (c *Connection) SetUnitSystem(system UnitSystem) {
+	c.unitSystem = system
+}
@@ -0,0 +1,85 @@
+package kw1281
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConvertUnits(t *testing.T) {
+	testInputs := []struct {
+		Data          []byte
+		Units         string
+		ExpectedValue float64
+	}{
+		// 78.4 C
+		{[]byte{0x05, 0x07, 0xd4}, "F", 173.12},
+		// 100 km/h
+		{[]byte{0x07, 0x64, 0x64}, "mph", 62.137119223733},
+		// 5 bar
+		{[]byte{0x0e, 0x14, 0x32}, "psi", 72.518868865},
+		// 2.5 l/h
+		{[]byte{0x23, 0x0a, 0x19}, "gal/h", 0.660430131},
+	}
+
+	for n, input := range testInputs {
+		m := dataToType(input.Data)
+		converted, err := m.Convert(input.Units)
+		assert.NoError(t, err, "input %v", n)
+		assert.Equal(t, input.Units, converted.Units)
+		assert.InDelta(t, input.ExpectedValue, converted.Value, 1e-6, "input %v", n)
+		assert.Equal(t, m.Raw, converted.Raw, "raw data is preserved")
+		assert.NotEqual(t, converted.Units, m.Units, "original value is unchanged")
+	}
+
+	m := dataToType([]byte{0x05, 0x07, 0xd4})
+	same, err := m.Convert("C")
+	assert.NoError(t, err)
+	assert.Equal(t, m, same)
+
+	back, err := m.ConvertTo(UnitSystemUS).Convert("C")
+	assert.NoError(t, err)
+	assert.InDelta(t, 78.4, back.Value, 1e-9)
+
+	_, err = m.Convert("km/h")
+	assert.Error(t, err, "incompatible units")
+
+	_, err = dataToType([]byte{0x0a, 0x01, 0x00}).Convert("F")
+	assert.Error(t, err, "text values can't be converted")
+}
+
+func TestConvertToUnitSystem(t *testing.T) {
+	m := dataToType([]byte{0x07, 0x64, 0x64})
+	assert.Equal(t, m, m.ConvertTo(UnitSystemMetric))
+	assert.Equal(t, "mph", m.ConvertTo(UnitSystemUS).Units)
+
+	// values without a preferred unit are unchanged
+	rpm := dataToType([]byte{0x01, 0xc8, 0x31})
+	assert.Equal(t, rpm, rpm.ConvertTo(UnitSystemUS))
+}
+
+func TestReadGroupUnitSystem(t *testing.T) {
+	c, m := connection()
+	counter := uint8(1)
+	c.SetUnitSystem(UnitSystemUS)
+
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+	ecuSendBytes(m, &counter, BlockTypeGetMeasurementGroup, []byte{byte(GroupRPMCoolantTemp)})
+	ecuSendBytes(m, &counter, BlockTypeMeasurementGroup, []byte{
+		0x01, 0xc8, 0x31,
+		0x05, 0x07, 0xd4,
+		0x01, 0xc8, 0x31,
+		0x01, 0xc8, 0x31})
+	ecuSendBytes(m, &counter, BlockTypeACK, []byte{})
+
+	var measurements []*Measurement
+	err := runRequest(t, c, Callbacks{}, func() (err error) {
+		measurements, err = c.ReadGroup(context.Background(), GroupRPMCoolantTemp)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "RPM", measurements[0].Units)
+	assert.Equal(t, "F", measurements[1].Units)
+	assert.InDelta(t, 173.12, measurements[1].Value, 1e-9)
+	assert.Equal(t, [3]byte{0x05, 0x07, 0xd4}, measurements[1].Raw)
+}
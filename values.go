@@ -0,0 +1,78 @@
+package kw1281
+
+// ValueKind describes how the value of a measurement should be interpreted
+type ValueKind int
+
+const (
+	// KindNumeric values are integers or floats, available from Float
+	KindNumeric ValueKind = iota + 1
+	// KindText values are enumerations or text such as "WARM", available from Text
+	KindText
+	// KindBitfield values are a set of bits, available from Bytes
+	KindBitfield
+	// KindRaw values could not be decoded, the raw bytes are available from Bytes
+	KindRaw
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case KindNumeric:
+		return "numeric"
+	case KindText:
+		return "text"
+	case KindBitfield:
+		return "bitfield"
+	case KindRaw:
+		return "raw"
+	}
+	return "unknown"
+}
+
+// constructors for each kind of value, used by every formula so the typed value is always set
+
+func numericValue(units string, v float64) MeasurementValue {
+	return MeasurementValue{Value: v, Units: units, kind: KindNumeric, number: v}
+}
+
+// integer values are delivered as an int in Value for compatibility, but are otherwise numeric
+func integerValue(units string, v int) MeasurementValue {
+	return MeasurementValue{Value: v, Units: units, kind: KindNumeric, number: float64(v)}
+}
+
+func textValue(units string, v string) MeasurementValue {
+	return MeasurementValue{Value: v, Units: units, kind: KindText, text: v}
+}
+
+func bitfieldValue(units string, v []byte) MeasurementValue {
+	return MeasurementValue{Value: v, Units: units, kind: KindBitfield, bytes: v}
+}
+
+func rawValue(raw [3]byte) *MeasurementValue {
+	return &MeasurementValue{
+		Value:     raw[:],
+		Undecoded: true,
+		Raw:       raw,
+		kind:      KindRaw,
+		bytes:     raw[:],
+	}
+}
+
+// Kind returns how the value should be interpreted
+func (m *MeasurementValue) Kind() ValueKind {
+	return m.kind
+}
+
+// Float returns a numeric value as a float64, or false if the value is not numeric
+func (m *MeasurementValue) Float() (float64, bool) {
+	return m.number, m.kind == KindNumeric
+}
+
+// Text returns a text value, or false if the value is not text
+func (m *MeasurementValue) Text() (string, bool) {
+	return m.text, m.kind == KindText
+}
+
+// Bytes returns the value of a bitfield or the raw bytes of an undecoded value, or false for other kinds
+func (m *MeasurementValue) Bytes() ([]byte, bool) {
+	return m.bytes, m.kind == KindBitfield || m.kind == KindRaw
+}
@@ -0,0 +1,55 @@
+package kw1281
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValueKinds(t *testing.T) {
+	testInputs := []struct {
+		Data   []byte
+		Kind   ValueKind
+		Float  float64
+		String string
+	}{
+		{[]byte{0x01, 0xc8, 0x31}, KindNumeric, 392, "392 RPM"},
+		{[]byte{0x05, 0x07, 0xd4}, KindNumeric, 78.4, "78.4 C"},
+		{[]byte{0x0a, 0x00, 0x00}, KindText, 0, "COLD "},
+		{[]byte{0x10, 0x1f, 0x02}, KindBitfield, 0, "[2 31] -"},
+		{[]byte{0xfe, 0x12, 0x34}, KindRaw, 0, "unknown formula 254: 0x12 0x34"},
+	}
+
+	for n, input := range testInputs {
+		m := dataToType(input.Data)
+		assert.Equal(t, input.Kind, m.Kind(), "wrong kind for input %v", n)
+		assert.Equal(t, input.String, m.String(), "string output changed for input %v", n)
+
+		f, ok := m.Float()
+		assert.Equal(t, input.Kind == KindNumeric, ok, "float availability for input %v", n)
+		assert.Equal(t, input.Float, f, "wrong float for input %v", n)
+
+		_, ok = m.Text()
+		assert.Equal(t, input.Kind == KindText, ok, "text availability for input %v", n)
+
+		_, ok = m.Bytes()
+		assert.Equal(t, input.Kind == KindBitfield || input.Kind == KindRaw, ok,
+			"bytes availability for input %v", n)
+	}
+}
+
+func TestValueKindConverted(t *testing.T) {
+	m := dataToType([]byte{0x05, 0x07, 0xd4}).ConvertTo(UnitSystemUS)
+	assert.Equal(t, KindNumeric, m.Kind())
+	f, ok := m.Float()
+	assert.True(t, ok)
+	assert.Equal(t, m.Value, f)
+
+	// values that weren't built by a formula have no kind rather than being reported as raw
+	assert.Equal(t, ValueKind(0), (&MeasurementValue{Value: 1}).Kind())
+}
+
+func TestValueKindString(t *testing.T) {
+	assert.Equal(t, "numeric", KindNumeric.String())
+	assert.Equal(t, "raw", KindRaw.String())
+	assert.Equal(t, "unknown", ValueKind(0).String())
+}